@@ -0,0 +1,101 @@
+package api2go
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/cention-sany/jsonapi"
+)
+
+// Attribute access modifiers, given as option of the jsonapi attr tag:
+//
+//	type User struct {
+//		ID        string    `jsonapi:"primary,users"`
+//		Name      string    `jsonapi:"attr,name"`
+//		CreatedAt time.Time `jsonapi:"attr,created-at,iso8601,readonly"`
+//		Password  string    `jsonapi:"attr,password,omitempty,writeonly"`
+//		Login     string    `jsonapi:"attr,login,createonly"`
+//	}
+//
+// A client sending a readonly attribute, or a createonly attribute on
+// update, gets 403 Forbidden with a source pointer to it. A writeonly
+// attribute is never part of a response, also not of included resources as
+// long as their type is added to the same API.
+const (
+	annotationAttribute  = "attr"
+	annotationReadOnly   = "readonly"
+	annotationWriteOnly  = "writeonly"
+	annotationCreateOnly = "createonly"
+)
+
+// attributeAccess holds the attributes of a resource with access modifiers.
+type attributeAccess struct {
+	readOnly, writeOnly, createOnly map[string]bool
+}
+
+// add records the access modifiers of the attribute name given as options of
+// its tag.
+func (a attributeAccess) add(name string, options []string) {
+	for _, option := range options {
+		switch option {
+		case annotationReadOnly:
+			a.readOnly[name] = true
+		case annotationWriteOnly:
+			a.writeOnly[name] = true
+		case annotationCreateOnly:
+			a.createOnly[name] = true
+		}
+	}
+}
+
+// checkPayload returns a 403 HTTPError for every attribute of the resource
+// object ro of a create or update request which the client may not write.
+func (a attributeAccess) checkPayload(ro *resourceObject, create bool) error {
+	if len(a.readOnly) == 0 && (create || len(a.createOnly) == 0) {
+		return nil
+	}
+	names := make([]string, 0, len(ro.Attributes))
+	for name := range ro.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	httpErr := NewHTTPError(nil, "Attributes may not be written",
+		http.StatusForbidden)
+	for _, name := range names {
+		pointer := "/data/attributes/" + name
+		if a.readOnly[name] {
+			httpErr.AddSourceError(pointer,
+				"The attribute "+name+" is read-only")
+		} else if !create && a.createOnly[name] {
+			httpErr.AddSourceError(pointer,
+				"The attribute "+name+" can only be set on creation")
+		}
+	}
+	if len(httpErr.E) > 0 {
+		return httpErr
+	}
+	return nil
+}
+
+// hideWriteOnly removes the writeonly attributes from node.
+func (api *API) hideWriteOnly(node *jsonapi.Node) {
+	if res := api.resource(node.Type); res != nil {
+		for name := range res.info.access.writeOnly {
+			delete(node.Attributes, name)
+		}
+	}
+}
+
+// hideWriteOnlyInDoc removes the writeonly attributes from all resource
+// objects of doc.
+func (api *API) hideWriteOnlyInDoc(doc *Doc) {
+	if one := doc.node(); one != nil {
+		api.hideWriteOnly(one)
+	}
+	for _, node := range doc.nodes() {
+		api.hideWriteOnly(node)
+	}
+	for _, node := range doc.included() {
+		api.hideWriteOnly(node)
+	}
+}
@@ -0,0 +1,124 @@
+package api2go_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/cention-sany/api2go"
+)
+
+type account struct {
+	ID       string `jsonapi:"primary,accounts"`
+	Name     string `jsonapi:"attr,name"`
+	Owner    string `jsonapi:"attr,owner,readonly"`
+	Password string `jsonapi:"attr,password,omitempty,writeonly"`
+	Login    string `jsonapi:"attr,login,createonly"`
+}
+
+func (a account) GetID() string { return a.ID }
+
+func (a *account) SetID(id string) error {
+	a.ID = id
+	return nil
+}
+
+type accountSource struct {
+	accounts map[string]*account
+}
+
+func (s *accountSource) FindOne(id string, req Request) (Responder, error) {
+	a, ok := s.accounts[id]
+	if !ok {
+		return nil, NewHTTPError(nil, "account not found", http.StatusNotFound)
+	}
+	c := *a
+	return &Response{Res: &c, Code: http.StatusOK}, nil
+}
+
+func (s *accountSource) Create(obj interface{}, req Request) (Responder,
+	error) {
+	a := obj.(*account)
+	a.ID = "1"
+	a.Owner = "server"
+	s.accounts[a.ID] = a
+	return &Response{Res: a, Code: http.StatusCreated}, nil
+}
+
+func (s *accountSource) Delete(id string, req Request) (Responder, error) {
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+func (s *accountSource) Update(obj interface{}, req Request) (Responder,
+	error) {
+	a := obj.(*account)
+	s.accounts[a.ID] = a
+	return &Response{Res: a, Code: http.StatusOK}, nil
+}
+
+func TestAttributeAccess(t *testing.T) {
+	router := NewHTTPRouter("/v1")
+	api := NewAPI("v1", NewStaticResolver(""))
+	source := &accountSource{accounts: map[string]*account{}}
+	api.AddResourceWithRouter(router, &account{}, source)
+
+	errorPointers := func(body string) []string {
+		var doc struct {
+			Errors []struct {
+				Source struct{ Pointer string }
+			}
+		}
+		json.Unmarshal([]byte(body), &doc)
+		var pointers []string
+		for _, e := range doc.Errors {
+			pointers = append(pointers, e.Source.Pointer)
+		}
+		return pointers
+	}
+
+	rec := serve(router, "POST", "/v1/accounts", `{"data":{"type":"accounts",
+		"attributes":{"name":"a","owner":"me","login":"l","password":"p"}}}`)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("Expect status %d but got %d.", http.StatusForbidden, rec.Code)
+	}
+	if p := errorPointers(rec.Body.String()); len(p) != 1 ||
+		p[0] != "/data/attributes/owner" {
+		t.Errorf("Expect a pointer to owner but got %v.", p)
+	}
+
+	rec = serve(router, "POST", "/v1/accounts", `{"data":{"type":"accounts",
+		"attributes":{"name":"a","login":"l","password":"secret"}}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expect status %d but got %d: %s", http.StatusCreated,
+			rec.Code, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), "password") {
+		t.Errorf("Expect no write-only password in %s", rec.Body)
+	}
+	if source.accounts["1"].Password != "secret" {
+		t.Errorf("Expect the password to be written but got %q.",
+			source.accounts["1"].Password)
+	}
+
+	rec = serve(router, "PATCH", "/v1/accounts/1", `{"data":{"type":"accounts",
+		"id":"1","attributes":{"login":"other","owner":"me"}}}`)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("Expect status %d but got %d.", http.StatusForbidden, rec.Code)
+	}
+	if p := errorPointers(rec.Body.String()); len(p) != 2 ||
+		p[0] != "/data/attributes/login" || p[1] != "/data/attributes/owner" {
+		t.Errorf("Expect pointers to login and owner but got %v.", p)
+	}
+
+	rec = serve(router, "PATCH", "/v1/accounts/1", `{"data":{"type":"accounts",
+		"id":"1","attributes":{"name":"b"}}}`)
+	if rec.Code != http.StatusOK || source.accounts["1"].Login != "l" {
+		t.Errorf("Expect an update of the name only but got %d: %s", rec.Code,
+			rec.Body)
+	}
+	rec = serve(router, "GET", "/v1/accounts/1", "")
+	if strings.Contains(rec.Body.String(), "password") {
+		t.Errorf("Expect no write-only password in %s", rec.Body)
+	}
+}
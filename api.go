@@ -1,11 +1,8 @@
 package api2go
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"log"
 	"net/http"
 	"net/url"
 	"reflect"
@@ -14,7 +11,6 @@ import (
 	"strings"
 
 	"github.com/cention-sany/jsonapi"
-	"github.com/gin-gonic/gin"
 )
 
 const (
@@ -44,7 +40,7 @@ type paginationQueryParams struct {
 	number, size, offset, limit string
 }
 
-func newPaginationQueryParams(c *gin.Context) paginationQueryParams {
+func newPaginationQueryParams(c *routeContext) paginationQueryParams {
 	var result paginationQueryParams
 	result.number = c.Query(jsonapi.QueryParamPageNumber)
 	result.size = c.Query(jsonapi.QueryParamPageSize)
@@ -69,7 +65,7 @@ func (p paginationQueryParams) isValid() bool {
 	return false
 }
 
-func (p paginationQueryParams) getLinks(c *gin.Context, count uint,
+func (p paginationQueryParams) getLinks(c *routeContext, count uint,
 	info information) (res *jsonapi.Links, err error) {
 	result := make(jsonapi.Links)
 	res = &result
@@ -181,9 +177,15 @@ type resource struct {
 	source       CRUD
 	name         string
 	api          *API
+	info         *typeInfo
+	ids          IDPolicy
+	generator    IDGenerator
+	bodyLimiter  BodySizeLimiter
+	replacer     RelationshipReplacer
+	editor       RelationshipEditor
 }
 
-func (api *API) addResource(rg *gin.RouterGroup, prototype Identifier,
+func (api *API) addResource(router Router, prototype Identifier,
 	source CRUD) *resource {
 	resourceType := reflect.TypeOf(prototype)
 	if resourceType.Kind() != reflect.Struct &&
@@ -199,20 +201,26 @@ func (api *API) addResource(rg *gin.RouterGroup, prototype Identifier,
 		ptrPrototype = reflect.ValueOf(prototype).Interface()
 	}
 
-	relation, err := findRelations(reflect.TypeOf(prototype), map[string]bool{})
+	ti, err := typeInfoOf(resourceType)
 	if err != nil {
 		panic(fmt.Sprint("invalid node:", err))
 	}
-	name := relation.typ
+	name := ti.name
 
 	res := resource{
 		resourceType: resourceType,
 		name:         name,
 		source:       source,
 		api:          api,
+		info:         ti,
+		ids:          idPolicyOf(source),
 	}
+	res.generator, _ = sourceAs[IDGenerator](source)
+	res.bodyLimiter, _ = sourceAs[BodySizeLimiter](source)
+	res.replacer, _ = sourceAs[RelationshipReplacer](source)
+	res.editor, _ = sourceAs[RelationshipEditor](source)
 
-	requestInfo := func(c *gin.Context, api *API) *information {
+	requestInfo := func(c *routeContext, api *API) *information {
 		var info *information
 		resolver, ok := api.information.resolver.(RequestAwareURLResolver)
 		if ok {
@@ -230,17 +238,34 @@ func (api *API) addResource(rg *gin.RouterGroup, prototype Identifier,
 
 	baseURL := "/" + name
 
-	rg.Handle("OPTIONS", baseURL, func(c *gin.Context) {
-		c.Header("Allow", "GET,POST,PATCH,OPTIONS")
+	// every route is instrumented, traced and recovers panics of the data
+	// source
+	handle := func(method, path, action string, h handler) {
+		h = api.instrument(name, action, api.trace(name, action,
+			api.recovery(h)))
+		router.Handle(method, path, func(w http.ResponseWriter,
+			r *http.Request, params Params) {
+			h(newRouteContext(w, r, params))
+		})
+	}
+
+	// every GET route answers HEAD with the same headers and no body
+	get := func(path, action, headAction string, h handler) {
+		handle("GET", path, action, h)
+		handle("HEAD", path, headAction, head(h))
+	}
+
+	handle("OPTIONS", baseURL, ActionOptions, func(c *routeContext) {
+		c.Header("Allow", "GET,HEAD,POST,PATCH,OPTIONS")
 		c.Writer.WriteHeader(http.StatusNoContent)
 	})
 
-	rg.Handle("OPTIONS", baseURL+"/:id", func(c *gin.Context) {
-		c.Header("Allow", "GET,PATCH,DELETE,OPTIONS")
+	handle("OPTIONS", baseURL+"/:id", ActionOptions, func(c *routeContext) {
+		c.Header("Allow", "GET,HEAD,PATCH,DELETE,OPTIONS")
 		c.Writer.WriteHeader(http.StatusNoContent)
 	})
 
-	rg.Handle("GET", baseURL, func(c *gin.Context) {
+	get(baseURL, ActionIndex, ActionHeadIndex, func(c *routeContext) {
 		info := requestInfo(c, api)
 		err := res.handleIndex(c, *info)
 		if err != nil {
@@ -248,7 +273,7 @@ func (api *API) addResource(rg *gin.RouterGroup, prototype Identifier,
 		}
 	})
 
-	rg.Handle("GET", baseURL+"/:id", func(c *gin.Context) {
+	get(baseURL+"/:id", ActionRead, ActionHeadRead, func(c *routeContext) {
 		info := requestInfo(c, api)
 		err := res.handleRead(c, *info)
 		if err != nil {
@@ -257,10 +282,10 @@ func (api *API) addResource(rg *gin.RouterGroup, prototype Identifier,
 	})
 
 	// generate all routes for linked relations if there are relations
-	if len(relation.relations) > 0 {
-		for _, rl := range relation.relations {
-			rg.Handle("GET", baseURL+"/:id/relationships/"+rl.name, func(relation relationship) gin.HandlerFunc {
-				return func(c *gin.Context) {
+	if len(ti.relations) > 0 {
+		for _, rl := range ti.relations {
+			get(baseURL+"/:id/relationships/"+rl.name, ActionReadRelationship, ActionHeadReadRelationship, func(relation relationship) handler {
+				return func(c *routeContext) {
 					info := requestInfo(c, api)
 					err := res.handleReadRelation(c, *info, relation)
 					if err != nil {
@@ -269,8 +294,8 @@ func (api *API) addResource(rg *gin.RouterGroup, prototype Identifier,
 				}
 			}(*rl))
 
-			rg.Handle("GET", baseURL+"/:id/"+rl.name, func(relation relationship) gin.HandlerFunc {
-				return func(c *gin.Context) {
+			get(baseURL+"/:id/"+rl.name, ActionReadLinked, ActionHeadReadLinked, func(relation relationship) handler {
+				return func(c *routeContext) {
 					info := requestInfo(c, api)
 					err := res.handleLinked(c, api, relation, *info)
 					if err != nil {
@@ -279,29 +304,33 @@ func (api *API) addResource(rg *gin.RouterGroup, prototype Identifier,
 				}
 			}(*rl))
 
-			rg.Handle("PATCH", baseURL+"/:id/relationships/"+rl.name, func(relation relationship) gin.HandlerFunc {
-				return func(c *gin.Context) {
-					err := res.handleReplaceRelation(c, relation)
+			handle("PATCH", baseURL+"/:id/relationships/"+rl.name, ActionReplaceRelationship, func(relation relationship) handler {
+				return func(c *routeContext) {
+					info := requestInfo(c, api)
+					err := res.handleReplaceRelation(c, *info, relation)
 					if err != nil {
 						api.handleError(err, c)
 					}
 				}
 			}(*rl))
 
-			if _, ok := ptrPrototype.(EditToManyRelations); ok && rl.isMany {
+			_, editable := ptrPrototype.(EditToManyRelations)
+			if (editable || res.editor != nil) && rl.isMany {
 				// generate additional routes to manipulate to-many relationships
-				rg.Handle("POST", baseURL+"/:id/relationships/"+rl.name, func(relation relationship) gin.HandlerFunc {
-					return func(c *gin.Context) {
-						err := res.handleAddToManyRelation(c, relation)
+				handle("POST", baseURL+"/:id/relationships/"+rl.name, ActionAddRelationship, func(relation relationship) handler {
+					return func(c *routeContext) {
+						info := requestInfo(c, api)
+						err := res.handleAddToManyRelation(c, *info, relation)
 						if err != nil {
 							api.handleError(err, c)
 						}
 					}
 				}(*rl))
 
-				rg.Handle("DELETE", baseURL+"/:id/relationships/"+rl.name, func(relation relationship) gin.HandlerFunc {
-					return func(c *gin.Context) {
-						err := res.handleDeleteToManyRelation(c, relation)
+				handle("DELETE", baseURL+"/:id/relationships/"+rl.name, ActionDeleteRelationship, func(relation relationship) handler {
+					return func(c *routeContext) {
+						info := requestInfo(c, api)
+						err := res.handleDeleteToManyRelation(c, *info, relation)
 						if err != nil {
 							api.handleError(err, c)
 						}
@@ -311,7 +340,7 @@ func (api *API) addResource(rg *gin.RouterGroup, prototype Identifier,
 		}
 	}
 
-	rg.Handle("POST", baseURL, func(c *gin.Context) {
+	handle("POST", baseURL, ActionCreate, func(c *routeContext) {
 		info := requestInfo(c, api)
 		err := res.handleCreate(c, info.prefix, *info)
 		if err != nil {
@@ -319,14 +348,14 @@ func (api *API) addResource(rg *gin.RouterGroup, prototype Identifier,
 		}
 	})
 
-	rg.Handle("DELETE", baseURL+"/:id", func(c *gin.Context) {
+	handle("DELETE", baseURL+"/:id", ActionDelete, func(c *routeContext) {
 		err := res.handleDelete(c)
 		if err != nil {
 			api.handleError(err, c)
 		}
 	})
 
-	rg.Handle("PATCH", baseURL+"/:id", func(c *gin.Context) {
+	handle("PATCH", baseURL+"/:id", ActionUpdate, func(c *routeContext) {
 		info := requestInfo(c, api)
 		err := res.handleUpdate(c, *info)
 		if err != nil {
@@ -339,11 +368,12 @@ func (api *API) addResource(rg *gin.RouterGroup, prototype Identifier,
 	return &res
 }
 
-func buildReqParams(c *gin.Context) Request {
+func buildReqParams(c *routeContext) Request {
 	req := Request{}
 	params := make(map[string][]string)
 	pagination := make(map[string]string)
-	for key, values := range c.Request.URL.Query() {
+	query := c.Request.URL.Query()
+	for key, values := range query {
 		params[key] = strings.Split(values[0], ",")
 		pageMatches := queryPageRegex.FindStringSubmatch(key)
 		if len(pageMatches) > 1 {
@@ -352,31 +382,72 @@ func buildReqParams(c *gin.Context) Request {
 	}
 	req.Pagination = pagination
 	req.QueryParams = params
-	req.APIContexter = c
+	if c.Request.Method == http.MethodGet || isHead(c) {
+		// objects found for an update must be complete
+		req.Fields = parseQueryFields(&query)
+	}
+	req.APIContexter = c.apiContext()
 	req.Request = c.Request
 	return req
 }
 
-func (res *resource) marshalResponse(c *gin.Context, rsp interface{},
+func (res *resource) marshalResponse(c *routeContext, rsp interface{},
 	status int) error {
-	filtered, err := filterSparseFields(rsp, c)
+	if doc, ok := rsp.(*Doc); ok {
+		res.api.hideWriteOnlyInDoc(doc)
+	}
+	span := startSpan(c, SpanFilterSparseFields)
+	filtered, err := res.api.filterSparseFields(rsp, c)
+	endSpan(span, err)
 	if err != nil {
 		return err
 	}
-	result, err := json.Marshal(filtered)
+	buf := getBuffer()
+	defer putBuffer(buf)
+	span = startSpan(c, SpanEncode)
+	err = res.api.codec().Encode(buf, payload(filtered))
+	endSpan(span, err)
 	if err != nil {
 		return err
 	}
-	writeResult(c.Writer, result, status, res.api.ContentType)
+	if status == http.StatusOK && (c.Request.Method == http.MethodGet ||
+		isHead(c)) {
+		etag := etagOf(buf.Bytes())
+		c.Header("ETag", etag)
+		if etagMatch(c.Request.Header.Get("If-None-Match"), etag) {
+			c.Writer.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+	}
+	c.Header("Content-Length", strconv.Itoa(buf.Len()))
+	writeResult(c.Writer, buf.Bytes(), status, res.api.ContentType)
 	return nil
 }
 
-func (res *resource) handleIndex(c *gin.Context, info information) error {
+func (res *resource) handleIndex(c *routeContext, info information) error {
+	if isHead(c) {
+		if source, ok := sourceAs[Counter](res.source); ok {
+			return res.handleCount(c, source)
+		}
+	}
+	if ids, ok := filterIDs(c); ok {
+		if source, ok := sourceAs[FindMany](res.source); ok {
+			req, span := sourceRequest(c, SpanFindMany)
+			response, err := source.FindMany(ids, req)
+			endSpan(span, err)
+			if err != nil {
+				return err
+			}
+			return res.respondWith(c, response, info, http.StatusOK)
+		}
+	}
 	if source, ok := res.source.(PaginatedFindAll); ok {
 		pagination := newPaginationQueryParams(c)
 
 		if pagination.isValid() {
-			count, response, err := source.PaginatedFindAll(buildReqParams(c))
+			req, span := sourceRequest(c, SpanPaginatedFindAll)
+			count, response, err := source.PaginatedFindAll(req)
+			endSpan(span, err)
 			if err != nil {
 				return err
 			}
@@ -386,6 +457,7 @@ func (res *resource) handleIndex(c *gin.Context, info information) error {
 				return err
 			}
 
+			c.Header(TotalCountHeader, strconv.FormatUint(uint64(count), 10))
 			return res.respondWithPagination(c, response, info, http.StatusOK,
 				paginationLinks)
 		}
@@ -396,7 +468,9 @@ func (res *resource) handleIndex(c *gin.Context, info information) error {
 		return NewHTTPError(nil, "Resource does not implement the FindAll interface", http.StatusNotFound)
 	}
 
-	response, err := source.FindAll(buildReqParams(c))
+	req, span := sourceRequest(c, SpanFindAll)
+	response, err := source.FindAll(req)
+	endSpan(span, err)
 	if err != nil {
 		return err
 	}
@@ -406,23 +480,44 @@ func (res *resource) handleIndex(c *gin.Context, info information) error {
 
 const idStr = "id"
 
-func (res *resource) handleRead(c *gin.Context, info information) error {
+func (res *resource) handleRead(c *routeContext, info information) error {
+	if isHead(c) {
+		if source, ok := sourceAs[Exister](res.source); ok {
+			return res.handleExists(c, source)
+		}
+	}
 	id := c.Param(idStr)
-	response, err := res.source.FindOne(id, buildReqParams(c))
+	req, span := sourceRequest(c, SpanFindOne)
+	response, err := res.source.FindOne(id, req)
+	endSpan(span, err)
 	if err != nil {
 		return err
 	}
 	return res.respondWith(c, response, info, http.StatusOK)
 }
 
-func (res *resource) handleReadRelation(c *gin.Context, info information,
+func (res *resource) handleReadRelation(c *routeContext, info information,
 	relation relationship) error {
 	id := c.Param(idStr)
-	obj, err := res.source.FindOne(id, buildReqParams(c))
+	req, span := sourceRequest(c, SpanFindOne)
+	// the sparse fieldset applies to primary data, the linkage is read from
+	// the complete object
+	req.Fields = nil
+	obj, err := res.source.FindOne(id, req)
+	endSpan(span, err)
 	if err != nil {
 		return err
 	}
+	return res.respondWithRelation(c, obj, info, relation, http.StatusOK)
+}
+
+// respondWithRelation answers with the resource linkage of relation of the
+// object in obj.
+func (res *resource) respondWithRelation(c *routeContext, obj Responder,
+	info information, relation relationship, status int) error {
+	span := startSpan(c, SpanMarshalToDoc)
 	doc, err := marshalToDoc(obj.Result(), info)
+	endSpan(span, err)
 	if err != nil {
 		return err
 	}
@@ -444,25 +539,24 @@ func (res *resource) handleReadRelation(c *gin.Context, info information,
 			doc.meta(meta)
 		}
 	}
-	return res.marshalResponse(c, rel, http.StatusOK)
+	return res.marshalResponse(c, rel, status)
 }
 
 // try to find the referenced resource and call the findAll Method with referencing resource id as param
-func (res *resource) handleLinked(c *gin.Context, api *API,
+func (res *resource) handleLinked(c *routeContext, api *API,
 	linked relationship, info information) error {
 	id := c.Param("id")
 	for _, resource := range api.resources {
 		if resource.name == linked.typ {
-			request := buildReqParams(c)
-			request.QueryParams[res.name+"ID"] = []string{id}
-			request.QueryParams[res.name+"Name"] = []string{linked.name}
-
 			if source, ok := resource.source.(PaginatedFindAll); ok {
 				// check for pagination, otherwise normal FindAll
 				pagination := newPaginationQueryParams(c)
 				if pagination.isValid() {
-					var count uint
+					request, span := sourceRequest(c, SpanPaginatedFindAll)
+					request.QueryParams[res.name+"ID"] = []string{id}
+					request.QueryParams[res.name+"Name"] = []string{linked.name}
 					count, response, err := source.PaginatedFindAll(request)
+					endSpan(span, err)
 					if err != nil {
 						return err
 					}
@@ -482,7 +576,11 @@ func (res *resource) handleLinked(c *gin.Context, api *API,
 				return NewHTTPError(nil, "Resource does not implement the FindAll interface", http.StatusNotFound)
 			}
 
+			request, span := sourceRequest(c, SpanFindAll)
+			request.QueryParams[res.name+"ID"] = []string{id}
+			request.QueryParams[res.name+"Name"] = []string{linked.name}
 			obj, err := source.FindAll(request)
+			endSpan(span, err)
 			if err != nil {
 				return err
 			}
@@ -497,35 +595,60 @@ func (res *resource) handleLinked(c *gin.Context, api *API,
 	)
 }
 
-func (res *resource) handleCreate(c *gin.Context, prefix string,
+func (res *resource) handleCreate(c *routeContext, prefix string,
 	info information) error {
-	// Ok this is weird again, but reflect.New produces a pointer, so we need
-	// the pure type without pointer, otherwise we would have a pointer pointer
-	// type that we don't want.
-	resourceType := res.resourceType
-	if resourceType.Kind() == reflect.Ptr {
-		resourceType = resourceType.Elem()
-	}
-	newObj := reflect.New(resourceType).Interface()
+	newObj := res.info.newObject()
 	// Call InitializeObject if available to allow implementers change the
 	// object before calling Unmarshal.
 	if initSource, ok := res.source.(ObjectInitializer); ok {
 		initSource.InitializeObject(newObj)
 	}
-	defer c.Request.Body.Close()
-	err := jsonapi.UnmarshalPayload(c.Request.Body, newObj)
+	body, err := res.readBody(c)
+	if err != nil {
+		return err
+	}
+	ro, err := res.decodeResourceObject(body)
+	if err != nil {
+		return err
+	}
+	if err := checkResourceObject(ro, res.name, ""); err != nil {
+		return err
+	}
+	if err := res.info.access.checkPayload(ro, true); err != nil {
+		return err
+	}
+	id := ro.id()
+	if err := res.ids.check(id); err != nil {
+		return err
+	}
+	if err := res.api.checkIntegrity(c, res.relationshipRefs(ro)); err != nil {
+		return err
+	}
+	span := startSpan(c, SpanUnmarshalPayload)
+	err = unmarshal(body, ro, newObj)
+	endSpan(span, err)
 	if err != nil {
-		return NewHTTPError(nil, err.Error(), http.StatusNotAcceptable)
+		return invalidResource(err)
+	}
+	if id == "" && res.generator != nil {
+		req, span := sourceRequest(c, SpanGenerateID)
+		err = generateID(res.generator, newObj, req)
+		endSpan(span, err)
+		if err != nil {
+			return err
+		}
 	}
 	var response Responder
+	req, span := sourceRequest(c, SpanCreate)
 	if res.resourceType.Kind() == reflect.Struct {
 		// we have to dereference the pointer if user wants to use non pointer
 		// values
 		response, err = res.source.Create(reflect.ValueOf(newObj).Elem().Interface(),
-			buildReqParams(c))
+			req)
 	} else {
-		response, err = res.source.Create(newObj, buildReqParams(c))
+		response, err = res.source.Create(newObj, req)
 	}
+	endSpan(span, err)
 	if err != nil {
 		return err
 	}
@@ -556,29 +679,48 @@ func (res *resource) handleCreate(c *gin.Context, prefix string,
 	}
 }
 
-func (res *resource) handleUpdate(c *gin.Context, info information) error {
+func (res *resource) handleUpdate(c *routeContext, info information) error {
 	id := c.Param("id")
-	obj, err := res.source.FindOne(id, buildReqParams(c))
+	body, err := res.readBody(c)
+	if err != nil {
+		return err
+	}
+	ro, err := res.decodeResourceObject(body)
 	if err != nil {
 		return err
 	}
-	rc := c.Request.Body
+	if err := checkResourceObject(ro, res.name, id); err != nil {
+		return err
+	}
+	if err := res.info.access.checkPayload(ro, false); err != nil {
+		return err
+	}
+	if err := res.api.checkIntegrity(c, res.relationshipRefs(ro)); err != nil {
+		return err
+	}
+	req, span := sourceRequest(c, SpanFindOne)
+	obj, err := res.source.FindOne(id, req)
+	endSpan(span, err)
+	if err != nil {
+		return err
+	}
+	span = startSpan(c, SpanUnmarshalPayload)
 	// we have to make the Result to a pointer to unmarshal into it
 	updatingObj := reflect.ValueOf(obj.Result())
 	if updatingObj.Kind() == reflect.Struct {
-		updatingObjPtr := reflect.New(reflect.TypeOf(obj.Result()))
-		updatingObjPtr.Elem().Set(updatingObj)
-		err = jsonapi.UnmarshalPayload(rc, updatingObjPtr.Interface())
-		updatingObj = updatingObjPtr.Elem()
+		updatingObjPtr := res.info.pointerTo(obj.Result())
+		err = unmarshal(body, ro, updatingObjPtr)
+		updatingObj = reflect.ValueOf(updatingObjPtr).Elem()
 	} else {
-		err = jsonapi.UnmarshalPayload(rc, updatingObj.Interface())
+		err = unmarshal(body, ro, updatingObj.Interface())
 	}
-	rc.Close()
+	endSpan(span, err)
 	if err != nil {
-		return NewHTTPError(nil, err.Error(), http.StatusNotAcceptable)
+		return invalidResource(err)
 	}
-	response, err := res.source.Update(updatingObj.Interface(),
-		buildReqParams(c))
+	req, span = sourceRequest(c, SpanUpdate)
+	response, err := res.source.Update(updatingObj.Interface(), req)
+	endSpan(span, err)
 	if err != nil {
 		return err
 	}
@@ -586,7 +728,9 @@ func (res *resource) handleUpdate(c *gin.Context, info information) error {
 	case http.StatusOK:
 		updated := response.Result()
 		if updated == nil {
-			internalResponse, err := res.source.FindOne(id, buildReqParams(c))
+			req, span := sourceRequest(c, SpanFindOne)
+			internalResponse, err := res.source.FindOne(id, req)
+			endSpan(span, err)
 			if err != nil {
 				return err
 			}
@@ -610,183 +754,173 @@ func (res *resource) handleUpdate(c *gin.Context, info information) error {
 	}
 }
 
-func (res *resource) handleReplaceRelation(c *gin.Context,
-	relation relationship) error {
-	var (
-		err     error
-		editObj interface{}
-	)
-	id := c.Param(idStr)
-	response, err := res.source.FindOne(id, buildReqParams(c))
-	if err != nil {
-		return err
-	}
-	body, err := unmarshalRequest(c.Request)
-	if err != nil {
-		return err
-	}
-	inc := map[string]interface{}{}
-	err = json.Unmarshal(body, &inc)
+func (res *resource) handleReplaceRelation(c *routeContext,
+	info information, relation relationship) error {
+	data, err := res.decodeLinkage(c, relation)
 	if err != nil {
 		return err
 	}
-	data, ok := inc["data"]
-	if !ok {
-		return errors.New("Invalid object. Need a \"data\" object")
-	}
-	resType := reflect.TypeOf(response.Result()).Kind()
-	if resType == reflect.Struct {
-		editObj = getPointerToStruct(response.Result())
-	} else {
-		editObj = response.Result()
-	}
-	err = processRelationshipsData(data, relation.name, editObj)
-	if err != nil {
+	if err := res.api.checkIntegrity(c, data.refs(relation, "/data")); err != nil {
 		return err
 	}
-	if resType == reflect.Struct {
-		_, err = res.source.Update(reflect.ValueOf(editObj).Elem().Interface(),
-			buildReqParams(c))
-	} else {
-		_, err = res.source.Update(editObj, buildReqParams(c))
+	if res.replacer != nil {
+		var ids []string
+		if data.isMany {
+			ids = data.ids()
+		} else if data.one != nil {
+			ids = []string{data.one.ID}
+		}
+		id := c.Param(idStr)
+		req, span := sourceRequest(c, SpanReplaceRelationship)
+		response, err := res.replacer.ReplaceRelationship(id, relation.name,
+			ids, req)
+		endSpan(span, err)
+		if err != nil {
+			return err
+		}
+		return res.respondWithRelationChange(c, info, relation, id, response)
 	}
-	c.Writer.WriteHeader(http.StatusNoContent)
-	return err
+	return res.editRelation(c, info, relation, func(obj interface{}) error {
+		return processRelationshipsData(data, relation.name, obj)
+	})
 }
 
-func (res *resource) handleAddToManyRelation(c *gin.Context,
-	relation relationship) error {
-	var (
-		err     error
-		editObj interface{}
-	)
-	id := c.Param(idStr)
-	response, err := res.source.FindOne(id, buildReqParams(c))
+func (res *resource) handleAddToManyRelation(c *routeContext,
+	info information, relation relationship) error {
+	data, err := res.decodeLinkage(c, relation)
 	if err != nil {
 		return err
 	}
-	body, err := unmarshalRequest(c.Request)
-	if err != nil {
-		return err
+	if !data.isMany {
+		return NewSourceError(http.StatusBadRequest, "/data",
+			"Data must be an array with \"id\" and \"type\" field to edit to-many relationships")
 	}
-	inc := map[string]interface{}{}
-	err = json.Unmarshal(body, &inc)
-	if err != nil {
+	if err := res.api.checkIntegrity(c, data.refs(relation, "/data")); err != nil {
 		return err
 	}
-	data, ok := inc["data"]
-	if !ok {
-		return errors.New("Invalid object. Need a \"data\" object")
-	}
-	newRels, ok := data.([]interface{})
-	if !ok {
-		return fmt.Errorf("Data must be an array with \"id\" and \"type\" field to add new to-many relationships")
-	}
-	newIDs := []string{}
-	for _, newRel := range newRels {
-		casted, ok := newRel.(map[string]interface{})
-		if !ok {
-			return errors.New("entry in data object invalid")
+	if res.editor != nil {
+		id := c.Param(idStr)
+		req, span := sourceRequest(c, SpanAddToRelationship)
+		response, err := res.editor.AddToRelationship(id, relation.name,
+			data.ids(), req)
+		endSpan(span, err)
+		if err != nil {
+			return err
 		}
-		newID, ok := casted["id"].(string)
+		return res.respondWithRelationChange(c, info, relation, id, response)
+	}
+	return res.editRelation(c, info, relation, func(obj interface{}) error {
+		target, ok := obj.(EditToManyRelations)
 		if !ok {
-			return errors.New("no id field found inside data object")
+			return errors.New("target struct must implement jsonapi.EditToManyRelations")
 		}
-		newIDs = append(newIDs, newID)
-	}
-	resType := reflect.TypeOf(response.Result()).Kind()
-	if resType == reflect.Struct {
-		editObj = getPointerToStruct(response.Result())
-	} else {
-		editObj = response.Result()
-	}
-	targetObj, ok := editObj.(EditToManyRelations)
-	if !ok {
-		return errors.New("target struct must implement jsonapi.EditToManyRelations")
-	}
-	targetObj.AddToManyIDs(relation.name, newIDs)
-	if resType == reflect.Struct {
-		_, err = res.source.Update(reflect.ValueOf(targetObj).Elem().Interface(),
-			buildReqParams(c))
-	} else {
-		_, err = res.source.Update(targetObj, buildReqParams(c))
-	}
-	c.Writer.WriteHeader(http.StatusNoContent)
-	return err
+		return target.AddToManyIDs(relation.name, data.ids())
+	})
 }
 
-func (res *resource) handleDeleteToManyRelation(c *gin.Context,
-	relation relationship) error {
-	var (
-		err     error
-		editObj interface{}
-	)
-	id := c.Param(idStr)
-	response, err := res.source.FindOne(id, buildReqParams(c))
-	if err != nil {
-		return err
-	}
-	body, err := unmarshalRequest(c.Request)
-	if err != nil {
-		return err
-	}
-	inc := map[string]interface{}{}
-	err = json.Unmarshal(body, &inc)
+func (res *resource) handleDeleteToManyRelation(c *routeContext,
+	info information, relation relationship) error {
+	data, err := res.decodeLinkage(c, relation)
 	if err != nil {
 		return err
 	}
-	data, ok := inc["data"]
-	if !ok {
-		return errors.New("Invalid object. Need a \"data\" object")
-	}
-	newRels, ok := data.([]interface{})
-	if !ok {
-		return fmt.Errorf("Data must be an array with \"id\" and \"type\" field to add new to-many relationships")
+	if !data.isMany {
+		return NewSourceError(http.StatusBadRequest, "/data",
+			"Data must be an array with \"id\" and \"type\" field to edit to-many relationships")
 	}
-	obsoleteIDs := []string{}
-	for _, newRel := range newRels {
-		casted, ok := newRel.(map[string]interface{})
-		if !ok {
-			return errors.New("entry in data object invalid")
+	if res.editor != nil {
+		id := c.Param(idStr)
+		req, span := sourceRequest(c, SpanRemoveFromRelationship)
+		response, err := res.editor.RemoveFromRelationship(id, relation.name,
+			data.ids(), req)
+		endSpan(span, err)
+		if err != nil {
+			return err
 		}
-		obsoleteID, ok := casted["id"].(string)
+		return res.respondWithRelationChange(c, info, relation, id, response)
+	}
+	return res.editRelation(c, info, relation, func(obj interface{}) error {
+		target, ok := obj.(EditToManyRelations)
 		if !ok {
-			return errors.New("no id field found inside data object")
+			return errors.New("target struct must implement jsonapi.EditToManyRelations")
 		}
-		obsoleteIDs = append(obsoleteIDs, obsoleteID)
+		return target.DeleteToManyIDs(relation.name, data.ids())
+	})
+}
+
+// editRelation loads the object of the route, changes its relationship with
+// edit and stores it with Update.
+func (res *resource) editRelation(c *routeContext, info information,
+	relation relationship, edit func(obj interface{}) error) error {
+	id := c.Param(idStr)
+	req, span := sourceRequest(c, SpanFindOne)
+	response, err := res.source.FindOne(id, req)
+	endSpan(span, err)
+	if err != nil {
+		return err
 	}
+	var editObj interface{}
 	resType := reflect.TypeOf(response.Result()).Kind()
 	if resType == reflect.Struct {
-		editObj = getPointerToStruct(response.Result())
+		editObj = res.info.pointerTo(response.Result())
 	} else {
 		editObj = response.Result()
 	}
-	targetObj, ok := editObj.(EditToManyRelations)
-	if !ok {
-		return errors.New("target struct must implement jsonapi.EditToManyRelations")
+	if err := edit(editObj); err != nil {
+		return err
 	}
-	targetObj.DeleteToManyIDs(relation.name, obsoleteIDs)
+	req, span = sourceRequest(c, SpanUpdate)
 	if resType == reflect.Struct {
-		_, err = res.source.Update(reflect.ValueOf(targetObj).Elem().Interface(),
-			buildReqParams(c))
+		response, err = res.source.Update(reflect.ValueOf(editObj).Elem().Interface(),
+			req)
 	} else {
-		_, err = res.source.Update(targetObj, buildReqParams(c))
+		response, err = res.source.Update(editObj, req)
+	}
+	endSpan(span, err)
+	if err != nil {
+		return err
 	}
-	c.Writer.WriteHeader(http.StatusNoContent)
-	return err
+	return res.respondWithRelationChange(c, info, relation, id, response)
 }
 
-// returns a pointer to an interface{} struct
-func getPointerToStruct(oldObj interface{}) interface{} {
-	resType := reflect.TypeOf(oldObj)
-	ptr := reflect.New(resType)
-	ptr.Elem().Set(reflect.ValueOf(oldObj))
-	return ptr.Interface()
+// respondWithRelationChange answers the Responder of a relationship change
+// of the object with id like
+// http://jsonapi.org/format/#crud-updating-relationship-responses: 200 OK
+// with the resulting resource linkage, 202 Accepted or 204 No Content.
+func (res *resource) respondWithRelationChange(c *routeContext,
+	info information, relation relationship, id string,
+	response Responder) error {
+	if response == nil {
+		c.Writer.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	switch response.StatusCode() {
+	case http.StatusOK:
+		if response.Result() == nil {
+			req, span := sourceRequest(c, SpanFindOne)
+			found, err := res.source.FindOne(id, req)
+			endSpan(span, err)
+			if err != nil {
+				return err
+			}
+			response = found
+		}
+		return res.respondWithRelation(c, response, info, relation,
+			http.StatusOK)
+	case http.StatusAccepted, http.StatusNoContent:
+		c.Writer.WriteHeader(response.StatusCode())
+		return nil
+	default:
+		return fmt.Errorf("invalid status code %d from resource %s for relationship %s",
+			response.StatusCode(), res.name, relation.name)
+	}
 }
 
-func (res *resource) handleDelete(c *gin.Context) error {
+func (res *resource) handleDelete(c *routeContext) error {
 	id := c.Param(idStr)
-	response, err := res.source.Delete(id, buildReqParams(c))
+	req, span := sourceRequest(c, SpanDelete)
+	response, err := res.source.Delete(id, req)
+	endSpan(span, err)
 	if err != nil {
 		return err
 	}
@@ -819,9 +953,21 @@ func writeResult(w http.ResponseWriter, data []byte, status int, contentType str
 	w.Write(data)
 }
 
-func (res *resource) respondWith(c *gin.Context, obj Responder,
+func (res *resource) respondWith(c *routeContext, obj Responder,
 	info information, status int) error {
-	doc, err := marshalToDoc(obj.Result(), info)
+	result := obj.Result()
+	if it, ok := result.(ResultIterator); ok {
+		if !buffered(c) {
+			return res.stream(c, obj, it, info, status, nil)
+		}
+		var err error
+		if result, err = collect(it); err != nil {
+			return err
+		}
+	}
+	span := startSpan(c, SpanMarshalToDoc)
+	doc, err := marshalToDoc(result, info)
+	endSpan(span, err)
 	if err != nil {
 		return err
 	}
@@ -837,12 +983,27 @@ func (res *resource) respondWith(c *gin.Context, obj Responder,
 			doc.links(links)
 		}
 	}
+	if err := res.include(c, info, doc); err != nil {
+		return err
+	}
 	return res.marshalResponse(c, doc, status)
 }
 
-func (res *resource) respondWithPagination(c *gin.Context, obj Responder,
+func (res *resource) respondWithPagination(c *routeContext, obj Responder,
 	info information, status int, links *jsonapi.Links) error {
-	doc, err := marshalToDoc(obj.Result(), info)
+	result := obj.Result()
+	if it, ok := result.(ResultIterator); ok {
+		if !buffered(c) {
+			return res.stream(c, obj, it, info, status, links)
+		}
+		var err error
+		if result, err = collect(it); err != nil {
+			return err
+		}
+	}
+	span := startSpan(c, SpanMarshalToDoc)
+	doc, err := marshalToDoc(result, info)
+	endSpan(span, err)
 	if err != nil {
 		return err
 	}
@@ -853,19 +1014,17 @@ func (res *resource) respondWithPagination(c *gin.Context, obj Responder,
 			doc.meta(meta)
 		}
 	}
-	return res.marshalResponse(c, doc, status)
-}
-
-func unmarshalRequest(r *http.Request) ([]byte, error) {
-	defer r.Body.Close()
-	data, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		return nil, err
+	if err := res.include(c, info, doc); err != nil {
+		return err
 	}
-	return data, nil
+	return res.marshalResponse(c, doc, status)
 }
 
-func filterSparseFields(resp interface{}, c *gin.Context) (interface{}, error) {
+// filterSparseFields trims the resource objects of resp to the fields[type]
+// parameters. The fields of types added to the API are checked against their
+// metadata, so that an empty omitempty attribute is still a valid field.
+func (api *API) filterSparseFields(resp interface{},
+	c *routeContext) (interface{}, error) {
 	query := c.Request.URL.Query()
 	queryParams := parseQueryFields(&query)
 	if len(queryParams) < 1 {
@@ -877,7 +1036,7 @@ func filterSparseFields(resp interface{}, c *gin.Context) (interface{}, error) {
 		// single entry in data
 		one := document.node()
 		if one != nil {
-			errors := replaceAttributes(&queryParams, one)
+			errors := api.replaceFields(&queryParams, one)
 			for t, v := range errors {
 				wrongFields[t] = v
 			}
@@ -886,7 +1045,7 @@ func filterSparseFields(resp interface{}, c *gin.Context) (interface{}, error) {
 		many := document.nodes()
 		if many != nil {
 			for _, data := range many {
-				errors := replaceAttributes(&queryParams, data)
+				errors := api.replaceFields(&queryParams, data)
 				for t, v := range errors {
 					wrongFields[t] = v
 				}
@@ -895,133 +1054,130 @@ func filterSparseFields(resp interface{}, c *gin.Context) (interface{}, error) {
 
 		// included slice
 		for _, include := range document.included() {
-			errors := replaceAttributes(&queryParams, include)
+			errors := api.replaceFields(&queryParams, include)
 			for t, v := range errors {
 				wrongFields[t] = v
 			}
 		}
 
 		if len(wrongFields) > 0 {
-			httpError := NewHTTPError(nil, "Some requested fields were invalid",
-				http.StatusBadRequest)
-			for k, v := range wrongFields {
-				for _, field := range v {
-					httpError.E = append(httpError.E, &jsonapi.ErrorObject{
-						Status: http.StatusText(http.StatusBadRequest),
-						Code:   codeInvalidQueryFields,
-						Title: fmt.Sprintf(`Field "%s" does not exist for type "%s"`,
-							field, k),
-						Detail: "Please make sure you do only request existing fields",
-					})
-				}
-			}
-			return nil, httpError
+			return nil, invalidFields(wrongFields)
 		}
 	}
 	return resp, nil
 }
 
+// invalidFields returns the 400 HTTPError for the fields of wrongFields,
+// which are keyed by type.
+func invalidFields(wrongFields map[string][]string) error {
+	httpError := NewHTTPError(nil, "Some requested fields were invalid",
+		http.StatusBadRequest)
+	for k, v := range wrongFields {
+		for _, field := range v {
+			httpError.E = append(httpError.E, &jsonapi.ErrorObject{
+				Status: http.StatusText(http.StatusBadRequest),
+				Code:   codeInvalidQueryFields,
+				Title: fmt.Sprintf(`Field "%s" does not exist for type "%s"`,
+					field, k),
+				Detail: "Please make sure you do only request existing fields",
+			})
+		}
+	}
+	return httpError
+}
+
+// parseQueryFields returns the fields[type] parameters. An empty parameter
+// like fields[posts]= asks for no fields at all.
 func parseQueryFields(query *url.Values) (result map[string][]string) {
 	result = map[string][]string{}
 	for name, param := range *query {
 		matches := queryFieldsRegex.FindStringSubmatch(name)
 		if len(matches) > 1 {
 			match := matches[1]
-			result[match] = strings.Split(param[0], ",")
+			result[match] = []string{}
+			for _, field := range strings.Split(param[0], ",") {
+				if field != "" {
+					result[match] = append(result[match], field)
+				}
+			}
 		}
 	}
 	return
 }
 
-func filterAttributes(attributes map[string]interface{},
-	fields []string) (filteredAttributes map[string]interface{},
+// filterFields keeps the attributes and relationships listed in fields and
+// returns the fields which are neither. With the metadata ti of the type a
+// field is only wrong if the type has no such field, else if node lacks it.
+func filterFields(node *jsonapi.Node, fields []string, ti *typeInfo) (
+	attributes map[string]interface{}, relationships map[string]interface{},
 	wrongFields []string) {
 	wrongFields = []string{}
-	filteredAttributes = map[string]interface{}{}
+	attributes = map[string]interface{}{}
+	relationships = map[string]interface{}{}
 
 	for _, field := range fields {
-		if attribute, ok := attributes[field]; ok {
-			filteredAttributes[field] = attribute
-		} else {
+		if attribute, ok := node.Attributes[field]; ok {
+			attributes[field] = attribute
+		} else if relationship, ok := node.Relationships[field]; ok {
+			relationships[field] = relationship
+		} else if ti == nil || !ti.hasField(field) {
 			wrongFields = append(wrongFields, field)
 		}
 	}
 	return
 }
 
-func replaceAttributes(query *map[string][]string,
+// replaceFields trims node to the sparse fieldset of its type, if there is
+// one. Both attributes and relationships are fields as of the spec.
+func (api *API) replaceFields(query *map[string][]string,
 	node *jsonapi.Node) map[string][]string {
 	fieldType := node.Type
-	fields := (*query)[fieldType]
-	if len(fields) > 0 && len(node.Attributes) > 0 {
-		attributes, wrongFields := filterAttributes(node.Attributes, fields)
-		if len(wrongFields) > 0 {
-			return map[string][]string{
-				fieldType: wrongFields,
-			}
+	fields, ok := (*query)[fieldType]
+	if !ok {
+		return nil
+	}
+	var ti *typeInfo
+	if res := api.resource(fieldType); res != nil {
+		ti = res.info
+	}
+	attributes, relationships, wrongFields := filterFields(node, fields, ti)
+	if len(wrongFields) > 0 {
+		return map[string][]string{
+			fieldType: wrongFields,
 		}
-		node.Attributes = attributes
 	}
+	node.Attributes = attributes
+	node.Relationships = relationships
 	return nil
 }
 
-func (api *API) handleError(err error, c *gin.Context) {
-	log.Println(err)
+func (api *API) handleError(err error, c *routeContext) {
+	api.logger().Println(err)
 	if e, ok := err.(HTTPError); ok {
-		c.Render(e.status, e)
+		writeError(c.Writer, e)
 		return
 	}
-	c.Render(http.StatusInternalServerError, NewHTTPError(err, err.Error(),
+	writeError(c.Writer, NewHTTPError(err, err.Error(),
 		http.StatusInternalServerError))
 }
 
-// TODO: this can also be replaced with a struct into that we directly json.Unmarshal
-func processRelationshipsData(data interface{}, linkName string,
+func processRelationshipsData(data linkage, linkName string,
 	target interface{}) error {
-	hasOne, ok := data.(map[string]interface{})
-	if ok {
-		hasOneID, ok := hasOne["id"].(string)
-		if !ok {
-			return fmt.Errorf("data object must have a field id for %s",
-				linkName)
-		}
+	if !data.isMany {
 		target, ok := target.(UnmarshalToOneRelations)
 		if !ok {
 			return errors.New("target struct must implement interface UnmarshalToOneRelations")
 		}
-		target.SetToOneReferenceID(linkName, hasOneID)
-	} else if data == nil {
-		// this means that a to-one relationship must be deleted
-		target, ok := target.(UnmarshalToOneRelations)
-		if !ok {
-			return errors.New("target struct must implement interface UnmarshalToOneRelations")
+		// a null linkage means that a to-one relationship must be deleted
+		id := ""
+		if data.one != nil {
+			id = data.one.ID
 		}
-		target.SetToOneReferenceID(linkName, "")
-	} else {
-		hasMany, ok := data.([]interface{})
-		if !ok {
-			return fmt.Errorf("invalid data object or array, must be an object with \"id\" and \"type\" field for %s",
-				linkName)
-		}
-		target, ok := target.(UnmarshalToManyRelations)
-		if !ok {
-			return errors.New("target struct must implement interface UnmarshalToManyRelations")
-		}
-		hasManyIDs := []string{}
-		for _, entry := range hasMany {
-			data, ok := entry.(map[string]interface{})
-			if !ok {
-				return fmt.Errorf("entry in data array must be an object for %s",
-					linkName)
-			}
-			dataID, ok := data["id"].(string)
-			if !ok {
-				return fmt.Errorf("all data objects must have a field id for %s",
-					linkName)
-			}
-			hasManyIDs = append(hasManyIDs, dataID)
-		}
-		target.SetToManyReferenceIDs(linkName, hasManyIDs)
+		return target.SetToOneReferenceID(linkName, id)
 	}
-	return nil
+	toMany, ok := target.(UnmarshalToManyRelations)
+	if !ok {
+		return errors.New("target struct must implement interface UnmarshalToManyRelations")
+	}
+	return toMany.SetToManyReferenceIDs(linkName, data.ids())
 }
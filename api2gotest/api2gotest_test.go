@@ -0,0 +1,113 @@
+package api2gotest_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cention-sany/api2go"
+	"github.com/cention-sany/api2go/api2gotest"
+)
+
+type book struct {
+	ID    string `jsonapi:"primary,books"`
+	Title string `jsonapi:"attr,title"`
+	Pages int    `jsonapi:"attr,pages"`
+}
+
+func (b book) GetID() string { return b.ID }
+
+type books map[string]*book
+
+func (s books) FindAll(req api2go.Request) (api2go.Responder, error) {
+	res := []*book{}
+	for i := 1; i <= len(s); i++ {
+		res = append(res, s[fmt.Sprint(i)])
+	}
+	return &api2go.Response{Res: res, Code: http.StatusOK}, nil
+}
+
+func (s books) FindOne(id string, req api2go.Request) (api2go.Responder,
+	error) {
+	b, ok := s[id]
+	if !ok {
+		return nil, api2go.NewHTTPError(nil, "no book", http.StatusNotFound)
+	}
+	return &api2go.Response{Res: b, Code: http.StatusOK}, nil
+}
+
+func (s books) Create(obj interface{}, req api2go.Request) (api2go.Responder,
+	error) {
+	b := obj.(*book)
+	b.ID = fmt.Sprint(len(s) + 1)
+	s[b.ID] = b
+	return &api2go.Response{Res: b, Code: http.StatusCreated}, nil
+}
+
+func (s books) Delete(id string, req api2go.Request) (api2go.Responder,
+	error) {
+	delete(s, id)
+	return &api2go.Response{Code: http.StatusNoContent}, nil
+}
+
+func (s books) Update(obj interface{}, req api2go.Request) (api2go.Responder,
+	error) {
+	b := obj.(*book)
+	s[b.ID] = b
+	return &api2go.Response{Res: b, Code: http.StatusOK}, nil
+}
+
+func newServer() *api2gotest.Server {
+	return api2gotest.NewServer("v1").AddResource(&book{}, books{
+		"1": {ID: "1", Title: "Dune", Pages: 412},
+		"2": {ID: "2", Title: "Solaris", Pages: 204},
+	})
+}
+
+func TestServer(t *testing.T) {
+	srv := newServer()
+	srv.GET(t, "/v1/books").Do().
+		Status(http.StatusOK).
+		HeaderEquals("Content-Type", "application/vnd.api+json").
+		DataType("books").
+		DataIDs("1", "2").
+		Attribute("2", "pages", 204).
+		Attributes("1", map[string]interface{}{"title": "Dune", "pages": 412})
+	srv.POST(t, "/v1/books").Data(&book{Title: "Ubik", Pages: 202}).Do().
+		Status(http.StatusCreated).
+		DataIDs("3").
+		Attribute("3", "title", "Ubik")
+	srv.GET(t, "/v1/books/9").Do().
+		Status(http.StatusNotFound).
+		Error(http.StatusNotFound, "")
+	srv.DELETE(t, "/v1/books/3").Do().
+		Status(http.StatusNoContent).
+		EmptyBody()
+}
+
+// recorder collects failures instead of failing the test.
+type recorder struct {
+	testing.TB
+	msgs []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.msgs = append(r.msgs, fmt.Sprintf(format, args...))
+}
+
+func TestReadableDiff(t *testing.T) {
+	rec := &recorder{TB: t}
+	newServer().GET(rec, "/v1/books").Do().DataIDs("1", "3")
+	if len(rec.msgs) != 1 {
+		t.Fatalf("Expect one failure but got %d.", len(rec.msgs))
+	}
+	for _, exp := range []string{"GET /v1/books", "primary data IDs",
+		`-   "3"`, `+   "2"`} {
+		if !strings.Contains(rec.msgs[0], exp) {
+			t.Errorf("Expect %q in failure:\n%s", exp, rec.msgs[0])
+		}
+	}
+}
@@ -0,0 +1,479 @@
+package api2gotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cention-sany/api2go"
+	"github.com/cention-sany/jsonapi"
+)
+
+const (
+	defaultMissingID   = "api2go-conformance-missing"
+	unknownRelation    = "api2go-conformance-unknown"
+	defaultObjectCount = 3
+)
+
+// Violation is one breach of the api2go.CRUD contract found by Conformance.
+type Violation struct {
+	// Method is the data source method, e.g. "Create" or "AddToManyIDs".
+	Method string
+	// Msg describes what was expected and what happened instead.
+	Msg string
+}
+
+func (v Violation) String() string {
+	return v.Method + ": " + v.Msg
+}
+
+// Conformance checks a data source against the contract documented on
+// api2go.CRUD and the optional interfaces:
+//
+//   - Create, Update and Delete only use the allowed Responder status codes
+//   - Create followed by FindOne round trips attributes and relationships
+//   - FindOne reports a missing or deleted object as HTTPError with 404
+//   - FindAll returns created objects
+//   - PaginatedFindAll reports the total count and honours the page size
+//   - EditToManyRelations adds and removes IDs and rejects unknown names
+//
+// Only Prototype, Source and New are required.
+type Conformance struct {
+	// Prototype is the value given to api2go.API.AddResource.
+	Prototype api2go.Identifier
+	// Source is the data source under test.
+	Source api2go.CRUD
+	// New returns the i-th object to create. It must be of the same type as
+	// Prototype and carry attributes and relationships which are expected to
+	// survive a round trip through Create and FindOne.
+	New func(i int) api2go.Identifier
+	// Modify changes at least one attribute of obj for the Update check.
+	// Update is checked without a change if it is nil.
+	Modify func(obj api2go.Identifier) api2go.Identifier
+	// RelatedIDs returns at least two IDs which may be linked through the
+	// to-many relationship name. Synthetic IDs are used if it is nil.
+	RelatedIDs func(name string) []string
+	// Count is the number of objects created for the FindAll and
+	// PaginatedFindAll checks, 3 if zero.
+	Count int
+	// MissingID is an ID which no object has.
+	MissingID string
+}
+
+// Run reports every violation found by Check as an error of a subtest of t
+// named after the method.
+func (c Conformance) Run(t *testing.T) {
+	for _, v := range c.Check() {
+		v := v
+		t.Run(v.Method, func(t *testing.T) {
+			t.Error(v.Msg)
+		})
+	}
+}
+
+type checker struct {
+	Conformance
+	violations []Violation
+	si         serverInfo
+}
+
+type serverInfo struct{}
+
+func (serverInfo) GetBaseURL() string { return DefaultBaseURL }
+func (serverInfo) GetPrefix() string  { return "/" }
+
+func (c *checker) violate(method, format string, args ...interface{}) {
+	c.violations = append(c.violations, Violation{
+		Method: method,
+		Msg:    fmt.Sprintf(format, args...),
+	})
+}
+
+// Check runs all checks and returns the found violations.
+func (c Conformance) Check() []Violation {
+	if c.MissingID == "" {
+		c.MissingID = defaultMissingID
+	}
+	if c.Count <= 0 {
+		c.Count = defaultObjectCount
+	}
+	ch := &checker{Conformance: c}
+	ch.checkMissing()
+	created := ch.checkCreate()
+	ch.checkFindAll(created)
+	ch.checkPaginatedFindAll(created)
+	ch.checkEditToMany()
+	if len(created) > 0 {
+		ch.checkUpdate(created[0])
+		ch.checkDelete(created[0])
+	}
+	return ch.violations
+}
+
+// request builds the Request a data source gets from api2go for target.
+func request(method, target string) api2go.Request {
+	r := httptest.NewRequest(method, target, nil)
+	params := map[string][]string{}
+	pagination := map[string]string{}
+	for k, v := range r.URL.Query() {
+		params[k] = strings.Split(v[0], ",")
+		if strings.HasPrefix(k, "page[") && strings.HasSuffix(k, "]") {
+			pagination[k[5:len(k)-1]] = v[0]
+		}
+	}
+	return api2go.Request{
+		QueryParams:  params,
+		Pagination:   pagination,
+		APIContexter: api2go.NewAPIContext(r.Context()),
+		Request:      r,
+	}
+}
+
+func statusIn(code int, allowed ...int) bool {
+	for _, a := range allowed {
+		if code == a {
+			return true
+		}
+	}
+	return false
+}
+
+func isNotFound(err error) bool {
+	e, ok := err.(api2go.HTTPError)
+	return ok && e.Status() == http.StatusNotFound
+}
+
+// toResource marshals v like api2go does and decodes it into a Resource.
+func (c *checker) toResource(v interface{}) (*Resource, error) {
+	payload, err := jsonapi.MarshalOneWithSI(v, c.si)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(payload.Data)
+	if err != nil {
+		return nil, err
+	}
+	var res Resource
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func linkage(rels map[string]Relationship) map[string][]string {
+	m := map[string][]string{}
+	for name, rel := range rels {
+		ids := []string{}
+		for _, l := range rel.Data {
+			ids = append(ids, l.ID)
+		}
+		m[name] = ids
+	}
+	return m
+}
+
+// compare reports differences of attributes and relationship linkage between
+// the expected and the actual object.
+func (c *checker) compare(method string, exp, got interface{}) {
+	e, err := c.toResource(exp)
+	if err != nil {
+		c.violate(method, "can not marshal expected object: %v", err)
+		return
+	}
+	g, err := c.toResource(got)
+	if err != nil {
+		c.violate(method, "can not marshal returned object: %v", err)
+		return
+	}
+	if !reflect.DeepEqual(normalize(e.Attributes), normalize(g.Attributes)) {
+		c.violate(method, "attributes did not round trip (-expected +got):\n%s",
+			Diff(pretty(e.Attributes), pretty(g.Attributes)))
+	}
+	el, gl := linkage(e.Relationships), linkage(g.Relationships)
+	if !reflect.DeepEqual(el, gl) {
+		c.violate(method, "relationships did not round trip (-expected +got):\n%s",
+			Diff(pretty(el), pretty(gl)))
+	}
+}
+
+func (c *checker) checkMissing() {
+	_, err := c.Source.FindOne(c.MissingID, request("GET", "/"))
+	if !isNotFound(err) {
+		c.violate("FindOne", "expected HTTPError with status 404 for missing "+
+			"ID %q but got %v", c.MissingID, err)
+	}
+}
+
+// checkCreate creates Count objects and returns the ones that could be read
+// back.
+func (c *checker) checkCreate() []api2go.Identifier {
+	var created []api2go.Identifier
+	for i := 0; i < c.Count; i++ {
+		obj := c.New(i)
+		if reflect.TypeOf(obj) != reflect.TypeOf(c.Prototype) {
+			c.violate("Create", "New returned %T but the prototype is %T", obj,
+				c.Prototype)
+			return nil
+		}
+		rsp, err := c.Source.Create(obj, request("POST", "/"))
+		if err != nil {
+			c.violate("Create", "unexpected error: %v", err)
+			continue
+		}
+		code := rsp.StatusCode()
+		if !statusIn(code, http.StatusCreated, http.StatusAccepted,
+			http.StatusNoContent) {
+			c.violate("Create", "returned status %d, allowed are 201, 202 "+
+				"and 204", code)
+			continue
+		}
+		id := obj.GetID()
+		if code == http.StatusCreated {
+			res, ok := rsp.Result().(api2go.Identifier)
+			if !ok {
+				c.violate("Create", "status 201 requires the created object "+
+					"as Result but got %T", rsp.Result())
+				continue
+			}
+			if res.GetID() == "" {
+				c.violate("Create", "the created object has no ID")
+				continue
+			}
+			id = res.GetID()
+		}
+		if id == "" {
+			// accepted without a client generated ID, nothing to read back
+			continue
+		}
+		found, err := c.Source.FindOne(id, request("GET", "/"+id))
+		if err != nil {
+			c.violate("FindOne", "can not find created object %q: %v", id, err)
+			continue
+		}
+		c.compare("Create", obj, found.Result())
+		if o, ok := found.Result().(api2go.Identifier); ok {
+			created = append(created, o)
+		}
+	}
+	return created
+}
+
+func ids(v interface{}) map[string]bool {
+	m := map[string]bool{}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return nil
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if o, ok := rv.Index(i).Interface().(api2go.Identifier); ok {
+			m[o.GetID()] = true
+		}
+	}
+	return m
+}
+
+func (c *checker) checkFindAll(created []api2go.Identifier) {
+	source, ok := c.Source.(api2go.FindAll)
+	if !ok {
+		return
+	}
+	rsp, err := source.FindAll(request("GET", "/"))
+	if err != nil {
+		c.violate("FindAll", "unexpected error: %v", err)
+		return
+	}
+	found := ids(rsp.Result())
+	if found == nil {
+		c.violate("FindAll", "Result must be a slice but got %T", rsp.Result())
+		return
+	}
+	for _, o := range created {
+		if !found[o.GetID()] {
+			c.violate("FindAll", "created object %q is missing", o.GetID())
+		}
+	}
+}
+
+func (c *checker) checkPaginatedFindAll(created []api2go.Identifier) {
+	source, ok := c.Source.(api2go.PaginatedFindAll)
+	if !ok {
+		return
+	}
+	var total uint
+	for n, target := range []string{
+		"/?page[offset]=0&page[limit]=2",
+		"/?page[offset]=1&page[limit]=2",
+		"/?page[number]=1&page[size]=2",
+		"/?page[number]=2&page[size]=2",
+	} {
+		count, rsp, err := source.PaginatedFindAll(request("GET", target))
+		if err != nil {
+			c.violate("PaginatedFindAll", "%s: unexpected error: %v", target,
+				err)
+			continue
+		}
+		found := ids(rsp.Result())
+		if found == nil {
+			c.violate("PaginatedFindAll", "%s: Result must be a slice but got "+
+				"%T", target, rsp.Result())
+			continue
+		}
+		if len(found) > 2 {
+			c.violate("PaginatedFindAll", "%s: page holds %d objects, "+
+				"expected at most 2", target, len(found))
+		}
+		if count < uint(len(created)) {
+			c.violate("PaginatedFindAll", "%s: total count %d is less than "+
+				"the %d created objects", target, count, len(created))
+		}
+		if n == 0 {
+			total = count
+		} else if count != total {
+			c.violate("PaginatedFindAll", "%s: total count %d differs from "+
+				"%d of the first page", target, count, total)
+		}
+	}
+}
+
+// toManyRelations returns the names of the to-many relationships of t.
+func toManyRelations(t reflect.Type) []string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		args := strings.Split(f.Tag.Get("jsonapi"), ",")
+		if len(args) > 1 && args[0] == "relation" &&
+			f.Type.Kind() == reflect.Slice {
+			names = append(names, args[1])
+		}
+	}
+	return names
+}
+
+// pointerTo returns a pointer to a copy of obj if it is a struct.
+func pointerTo(obj interface{}) interface{} {
+	v := reflect.ValueOf(obj)
+	if v.Kind() == reflect.Ptr {
+		return obj
+	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return p.Interface()
+}
+
+func (c *checker) relationIDs(obj interface{}, name string) []string {
+	res, err := c.toResource(obj)
+	if err != nil {
+		return nil
+	}
+	return linkage(res.Relationships)[name]
+}
+
+func (c *checker) checkEditToMany() {
+	obj := pointerTo(c.New(0))
+	edit, ok := obj.(api2go.EditToManyRelations)
+	if !ok {
+		return
+	}
+	for _, name := range toManyRelations(reflect.TypeOf(c.Prototype)) {
+		related := []string{"1", "2"}
+		if c.RelatedIDs != nil {
+			related = c.RelatedIDs(name)
+		}
+		if len(related) < 2 {
+			c.violate("EditToManyRelations", "RelatedIDs(%q) must return at "+
+				"least 2 IDs", name)
+			continue
+		}
+		before := c.relationIDs(obj, name)
+		if err := edit.AddToManyIDs(name, related[:2]); err != nil {
+			c.violate("AddToManyIDs", "%q: unexpected error: %v", name, err)
+			continue
+		}
+		exp := append(append([]string{}, before...), related[:2]...)
+		if got := c.relationIDs(obj, name); !reflect.DeepEqual(exp, got) {
+			c.violate("AddToManyIDs", "%q: expected linkage %v but got %v",
+				name, exp, got)
+		}
+		if err := edit.DeleteToManyIDs(name, related[:1]); err != nil {
+			c.violate("DeleteToManyIDs", "%q: unexpected error: %v", name, err)
+			continue
+		}
+		exp = append(append([]string{}, before...), related[1])
+		if got := c.relationIDs(obj, name); !reflect.DeepEqual(exp, got) {
+			c.violate("DeleteToManyIDs", "%q: expected linkage %v but got %v",
+				name, exp, got)
+		}
+	}
+	if err := edit.AddToManyIDs(unknownRelation, []string{"1"}); err == nil {
+		c.violate("AddToManyIDs", "expected an error for unknown "+
+			"relationship %q", unknownRelation)
+	}
+	if err := edit.DeleteToManyIDs(unknownRelation, []string{"1"}); err == nil {
+		c.violate("DeleteToManyIDs", "expected an error for unknown "+
+			"relationship %q", unknownRelation)
+	}
+}
+
+func (c *checker) checkUpdate(obj api2go.Identifier) {
+	if c.Modify != nil {
+		obj = c.Modify(obj)
+	}
+	id := obj.GetID()
+	rsp, err := c.Source.Update(obj, request("PATCH", "/"+id))
+	if err != nil {
+		c.violate("Update", "unexpected error: %v", err)
+		return
+	}
+	code := rsp.StatusCode()
+	switch code {
+	case http.StatusOK:
+		if rsp.Result() != nil {
+			c.compare("Update", obj, rsp.Result())
+		}
+	case http.StatusAccepted:
+		// processing is delayed, nothing can be checked
+		return
+	case http.StatusNoContent:
+	default:
+		c.violate("Update", "returned status %d, allowed are 200, 202 and 204",
+			code)
+		return
+	}
+	found, err := c.Source.FindOne(id, request("GET", "/"+id))
+	if err != nil {
+		c.violate("FindOne", "can not find updated object %q: %v", id, err)
+		return
+	}
+	c.compare("Update", obj, found.Result())
+}
+
+func (c *checker) checkDelete(obj api2go.Identifier) {
+	id := obj.GetID()
+	rsp, err := c.Source.Delete(id, request("DELETE", "/"+id))
+	if err != nil {
+		c.violate("Delete", "unexpected error: %v", err)
+		return
+	}
+	code := rsp.StatusCode()
+	switch code {
+	case http.StatusAccepted:
+		return
+	case http.StatusOK, http.StatusNoContent:
+	default:
+		c.violate("Delete", "returned status %d, allowed are 200, 202 and 204",
+			code)
+		return
+	}
+	_, err = c.Source.FindOne(id, request("GET", "/"+id))
+	if !isNotFound(err) {
+		c.violate("FindOne", "expected HTTPError with status 404 for deleted "+
+			"ID %q but got %v", id, err)
+	}
+}
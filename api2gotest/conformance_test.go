@@ -0,0 +1,78 @@
+package api2gotest_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cention-sany/api2go"
+	"github.com/cention-sany/api2go/api2gotest"
+)
+
+func newBook(i int) api2go.Identifier {
+	return &book{Title: fmt.Sprint("Book ", i), Pages: 100 + i}
+}
+
+func TestConformance(t *testing.T) {
+	api2gotest.Conformance{
+		Prototype: &book{},
+		Source:    books{},
+		New:       newBook,
+		Modify: func(obj api2go.Identifier) api2go.Identifier {
+			b := *obj.(*book)
+			b.Title = "changed"
+			return &b
+		},
+	}.Run(t)
+}
+
+// sloppyBooks breaks the contract in a few ways.
+type sloppyBooks struct {
+	books
+}
+
+func (s sloppyBooks) FindOne(id string, req api2go.Request) (api2go.Responder,
+	error) {
+	if _, ok := s.books[id]; !ok {
+		return nil, errors.New("not there")
+	}
+	return s.books.FindOne(id, req)
+}
+
+func (s sloppyBooks) Create(obj interface{}, req api2go.Request) (
+	api2go.Responder, error) {
+	b := *obj.(*book)
+	b.ID = fmt.Sprint(len(s.books) + 1)
+	b.Pages = 0
+	s.books[b.ID] = &b
+	return &api2go.Response{Res: &b, Code: http.StatusOK}, nil
+}
+
+func (s sloppyBooks) Delete(id string, req api2go.Request) (api2go.Responder,
+	error) {
+	return &api2go.Response{Code: http.StatusNoContent}, nil
+}
+
+func TestConformanceViolations(t *testing.T) {
+	vs := api2gotest.Conformance{
+		Prototype: &book{},
+		Source:    sloppyBooks{books{}},
+		New:       newBook,
+		Count:     1,
+	}.Check()
+	var got []string
+	for _, v := range vs {
+		got = append(got, v.String())
+	}
+	all := strings.Join(got, "\n")
+	for _, exp := range []string{
+		`FindOne: expected HTTPError with status 404 for missing ID`,
+		`Create: returned status 200, allowed are 201, 202 and 204`,
+	} {
+		if !strings.Contains(all, exp) {
+			t.Errorf("Expect violation %q but got:\n%s", exp, all)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package api2gotest
+
+import (
+	"encoding/json"
+)
+
+// Identifier is a resource identifier object.
+type Identifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// Relationship is a relationship object of a Resource. Data holds the linkage
+// normalized to a slice, Many tells if it was a to-many relationship.
+type Relationship struct {
+	Data  []Identifier
+	Many  bool
+	Links map[string]interface{}
+	Meta  map[string]interface{}
+}
+
+// UnmarshalJSON normalizes to-one and to-many linkage.
+func (r *Relationship) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Data  json.RawMessage        `json:"data"`
+		Links map[string]interface{} `json:"links"`
+		Meta  map[string]interface{} `json:"meta"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	r.Links = raw.Links
+	r.Meta = raw.Meta
+	r.Data, r.Many = nil, false
+	if len(raw.Data) == 0 || string(raw.Data) == "null" {
+		return nil
+	}
+	if raw.Data[0] == '[' {
+		r.Many = true
+		return json.Unmarshal(raw.Data, &r.Data)
+	}
+	var one Identifier
+	if err := json.Unmarshal(raw.Data, &one); err != nil {
+		return err
+	}
+	r.Data = []Identifier{one}
+	return nil
+}
+
+// Resource is a resource object of a Document.
+type Resource struct {
+	Type          string                  `json:"type"`
+	ID            string                  `json:"id"`
+	Attributes    map[string]interface{}  `json:"attributes"`
+	Relationships map[string]Relationship `json:"relationships"`
+	Links         map[string]interface{}  `json:"links"`
+	Meta          map[string]interface{}  `json:"meta"`
+}
+
+// ErrorSource is the source member of an ErrorObject.
+type ErrorSource struct {
+	Pointer   string `json:"pointer"`
+	Parameter string `json:"parameter"`
+}
+
+// ErrorObject is an entry of the errors member of a Document.
+type ErrorObject struct {
+	ID     string                 `json:"id"`
+	Status string                 `json:"status"`
+	Code   string                 `json:"code"`
+	Title  string                 `json:"title"`
+	Detail string                 `json:"detail"`
+	Source *ErrorSource           `json:"source"`
+	Meta   map[string]interface{} `json:"meta"`
+}
+
+// Document is a decoded top-level JSON:API document. Data is normalized to a
+// slice, Many tells if the primary data was an array.
+type Document struct {
+	Data     []Resource
+	Many     bool
+	Included []Resource
+	Errors   []ErrorObject
+	Links    map[string]interface{}
+	Meta     map[string]interface{}
+}
+
+// UnmarshalJSON normalizes single and collection primary data.
+func (d *Document) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Data     json.RawMessage        `json:"data"`
+		Included []Resource             `json:"included"`
+		Errors   []ErrorObject          `json:"errors"`
+		Links    map[string]interface{} `json:"links"`
+		Meta     map[string]interface{} `json:"meta"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	d.Included = raw.Included
+	d.Errors = raw.Errors
+	d.Links = raw.Links
+	d.Meta = raw.Meta
+	d.Data, d.Many = nil, false
+	if len(raw.Data) == 0 || string(raw.Data) == "null" {
+		return nil
+	}
+	if raw.Data[0] == '[' {
+		d.Many = true
+		return json.Unmarshal(raw.Data, &d.Data)
+	}
+	var one Resource
+	if err := json.Unmarshal(raw.Data, &one); err != nil {
+		return err
+	}
+	d.Data = []Resource{one}
+	return nil
+}
+
+// find returns the resource with typ and id of list or nil. An empty typ
+// matches any type.
+func find(list []Resource, typ, id string) *Resource {
+	for i := range list {
+		if list[i].ID == id && (typ == "" || list[i].Type == typ) {
+			return &list[i]
+		}
+	}
+	return nil
+}
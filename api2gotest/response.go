@@ -0,0 +1,359 @@
+package api2gotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Response is a recorded response with assertions on its document. Every
+// assertion reports a failure to the testing.TB of the request and returns
+// the Response for chaining.
+type Response struct {
+	t    testing.TB
+	desc string
+	// Code is the http status code.
+	Code int
+	// Header holds the response headers.
+	Header http.Header
+	// Body is the raw response body.
+	Body []byte
+	doc  *Document
+	err  error
+}
+
+func newResponse(t testing.TB, desc string,
+	rec *httptest.ResponseRecorder) *Response {
+	return &Response{
+		t:      t,
+		desc:   desc,
+		Code:   rec.Code,
+		Header: rec.Header(),
+		Body:   rec.Body.Bytes(),
+	}
+}
+
+// Document decodes the body once and returns it. A body which is not a JSON
+// document fails the test.
+func (r *Response) Document() *Document {
+	r.t.Helper()
+	if r.doc == nil && r.err == nil {
+		doc := new(Document)
+		r.err = json.Unmarshal(r.Body, doc)
+		if r.err == nil {
+			r.doc = doc
+		}
+	}
+	if r.err != nil {
+		r.t.Fatalf("%s: body is not a JSON:API document: %v\n%s", r.desc,
+			r.err, r.Body)
+		return &Document{}
+	}
+	return r.doc
+}
+
+func (r *Response) errorf(format string, args ...interface{}) {
+	r.t.Helper()
+	r.t.Errorf("%s: %s", r.desc, fmt.Sprintf(format, args...))
+}
+
+// Status asserts the http status code.
+func (r *Response) Status(code int) *Response {
+	r.t.Helper()
+	if r.Code != code {
+		r.errorf("expected status %d but got %d\n%s", code, r.Code, r.Body)
+	}
+	return r
+}
+
+// HeaderEquals asserts the value of a response header.
+func (r *Response) HeaderEquals(key, value string) *Response {
+	r.t.Helper()
+	if got := r.Header.Get(key); got != value {
+		r.errorf("expected header %s %q but got %q", key, value, got)
+	}
+	return r
+}
+
+// EmptyBody asserts that nothing was written to the body.
+func (r *Response) EmptyBody() *Response {
+	r.t.Helper()
+	if len(r.Body) != 0 {
+		r.errorf("expected an empty body but got\n%s", r.Body)
+	}
+	return r
+}
+
+// DataIDs asserts the IDs of the primary data in order.
+func (r *Response) DataIDs(ids ...string) *Response {
+	r.t.Helper()
+	doc := r.Document()
+	got := make([]string, 0, len(doc.Data))
+	for _, res := range doc.Data {
+		got = append(got, res.ID)
+	}
+	r.equal("primary data IDs", append([]string{}, ids...), got)
+	return r
+}
+
+// DataType asserts that all primary data is of type typ.
+func (r *Response) DataType(typ string) *Response {
+	r.t.Helper()
+	for _, res := range r.Document().Data {
+		if res.Type != typ {
+			r.errorf("expected primary data of type %q but %s has type %q",
+				typ, res.ID, res.Type)
+		}
+	}
+	return r
+}
+
+// Attribute asserts the attribute key of the primary data resource with id.
+// value is compared by its JSON encoding so that e.g. numbers of any Go type
+// match.
+func (r *Response) Attribute(id, key string, value interface{}) *Response {
+	r.t.Helper()
+	res := find(r.Document().Data, "", id)
+	if res == nil {
+		r.errorf("no primary data with id %q", id)
+		return r
+	}
+	got, ok := res.Attributes[key]
+	if !ok {
+		r.errorf("resource %s/%s has no attribute %q, attributes are:\n%s",
+			res.Type, id, key, pretty(res.Attributes))
+		return r
+	}
+	r.equal(fmt.Sprintf("attribute %q of %s/%s", key, res.Type, id),
+		normalize(value), got)
+	return r
+}
+
+// Attributes asserts all attributes of the primary data resource with id.
+func (r *Response) Attributes(id string,
+	attrs map[string]interface{}) *Response {
+	r.t.Helper()
+	res := find(r.Document().Data, "", id)
+	if res == nil {
+		r.errorf("no primary data with id %q", id)
+		return r
+	}
+	r.equal(fmt.Sprintf("attributes of %s/%s", res.Type, id), normalize(attrs),
+		normalize(res.Attributes))
+	return r
+}
+
+// NoAttribute asserts that the primary data resource with id does not have
+// the attribute key.
+func (r *Response) NoAttribute(id, key string) *Response {
+	r.t.Helper()
+	res := find(r.Document().Data, "", id)
+	if res == nil {
+		r.errorf("no primary data with id %q", id)
+		return r
+	}
+	if v, ok := res.Attributes[key]; ok {
+		r.errorf("expected no attribute %q on %s/%s but got %s", key,
+			res.Type, id, pretty(v))
+	}
+	return r
+}
+
+// Relationship asserts the linkage IDs of relationship name of the primary
+// data resource with id.
+func (r *Response) Relationship(id, name string, ids ...string) *Response {
+	r.t.Helper()
+	res := find(r.Document().Data, "", id)
+	if res == nil {
+		r.errorf("no primary data with id %q", id)
+		return r
+	}
+	rel, ok := res.Relationships[name]
+	if !ok {
+		r.errorf("resource %s/%s has no relationship %q", res.Type, id, name)
+		return r
+	}
+	got := make([]string, 0, len(rel.Data))
+	for _, l := range rel.Data {
+		got = append(got, l.ID)
+	}
+	r.equal(fmt.Sprintf("relationship %q of %s/%s", name, res.Type, id),
+		append([]string{}, ids...), got)
+	return r
+}
+
+// Included asserts the included resources as "type/id" pairs, ignoring
+// their order.
+func (r *Response) Included(typeIDs ...string) *Response {
+	r.t.Helper()
+	inc := r.Document().Included
+	got := make([]string, 0, len(inc))
+	for _, res := range inc {
+		got = append(got, res.Type+"/"+res.ID)
+	}
+	r.equal("included resources", sorted(typeIDs), sorted(got))
+	return r
+}
+
+// IncludedAttribute asserts an attribute of the included resource typ/id.
+func (r *Response) IncludedAttribute(typ, id, key string,
+	value interface{}) *Response {
+	r.t.Helper()
+	res := find(r.Document().Included, typ, id)
+	if res == nil {
+		r.errorf("no included resource %s/%s", typ, id)
+		return r
+	}
+	r.equal(fmt.Sprintf("attribute %q of included %s/%s", key, typ, id),
+		normalize(value), res.Attributes[key])
+	return r
+}
+
+// Meta asserts a member of the top-level meta object.
+func (r *Response) Meta(key string, value interface{}) *Response {
+	r.t.Helper()
+	r.equal(fmt.Sprintf("meta %q", key), normalize(value),
+		r.Document().Meta[key])
+	return r
+}
+
+// Link asserts the href of a top-level link, e.g. "next".
+func (r *Response) Link(name, href string) *Response {
+	r.t.Helper()
+	l, ok := r.Document().Links[name]
+	if !ok {
+		r.errorf("no top-level link %q, links are:\n%s", name,
+			pretty(r.Document().Links))
+		return r
+	}
+	got := ""
+	switch v := l.(type) {
+	case string:
+		got = v
+	case map[string]interface{}:
+		got, _ = v["href"].(string)
+	}
+	r.equal(fmt.Sprintf("link %q", name), href, got)
+	return r
+}
+
+// Error asserts that the document holds an error with the http status code
+// and, if not empty, the source pointer.
+func (r *Response) Error(status int, pointer string) *Response {
+	r.t.Helper()
+	errs := r.Document().Errors
+	s := strconv.Itoa(status)
+	for _, e := range errs {
+		if e.Status != s && e.Status != http.StatusText(status) {
+			continue
+		}
+		if pointer == "" || (e.Source != nil && e.Source.Pointer == pointer) {
+			return r
+		}
+	}
+	r.errorf("expected an error with status %d and pointer %q, errors are:\n%s",
+		status, pointer, pretty(errs))
+	return r
+}
+
+// ErrorCode asserts that the document holds an error with the application
+// specific code.
+func (r *Response) ErrorCode(code string) *Response {
+	r.t.Helper()
+	errs := r.Document().Errors
+	for _, e := range errs {
+		if e.Code == code {
+			return r
+		}
+	}
+	r.errorf("expected an error with code %q, errors are:\n%s", code,
+		pretty(errs))
+	return r
+}
+
+// equal compares exp and got and reports a line diff of their JSON encoding
+// if they differ.
+func (r *Response) equal(what string, exp, got interface{}) {
+	r.t.Helper()
+	if reflect.DeepEqual(exp, got) {
+		return
+	}
+	e, g := pretty(exp), pretty(got)
+	if e == g {
+		return
+	}
+	r.errorf("unexpected %s (-expected +got):\n%s", what, Diff(e, g))
+}
+
+// normalize round trips v through JSON so that it compares equal to decoded
+// documents.
+func normalize(v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var n interface{}
+	if err := json.Unmarshal(b, &n); err != nil {
+		return v
+	}
+	return n
+}
+
+func pretty(v interface{}) string {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("%#v", v)
+	}
+	return string(b)
+}
+
+func sorted(s []string) []string {
+	c := append([]string{}, s...)
+	sort.Strings(c)
+	return c
+}
+
+// Diff returns a line based diff of a and b. Lines only in a are prefixed
+// with "-", lines only in b with "+".
+func Diff(a, b string) string {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+	// longest common subsequence table
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			out.WriteString("  " + x[i] + "\n")
+			i++
+			j++
+		case j < len(y) && (i == len(x) || lcs[i][j+1] >= lcs[i+1][j]):
+			out.WriteString("+ " + y[j] + "\n")
+			j++
+		default:
+			out.WriteString("- " + x[i] + "\n")
+			i++
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,181 @@
+// Package api2gotest provides helpers to test JSON:API endpoints served by
+// api2go without a network listener.
+//
+// A Server wires an api2go.API onto an in-memory gin engine, requests are
+// built fluently and the returned documents can be checked with typed
+// assertions:
+//
+//	srv := api2gotest.NewServer("v1").
+//		AddResource(&model.User{}, userSource)
+//	srv.GET(t, "/v1/users").Query("page[size]", "2").Do().
+//		Status(http.StatusOK).
+//		DataIDs("1", "2")
+package api2gotest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/cention-sany/api2go"
+	"github.com/cention-sany/jsonapi"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultBaseURL is the base URL used by NewServer for generated links.
+const DefaultBaseURL = "http://localhost"
+
+// Server is an api2go.API mounted on an in-memory gin engine.
+type Server struct {
+	// Engine serves all requests. Additional routes may be added to it.
+	Engine *gin.Engine
+	// API is the api2go.API under test. Its Logger, Instrumentation etc. may
+	// be set directly.
+	API   *api2go.API
+	group *gin.RouterGroup
+}
+
+// NewServer creates a Server with prefix for the API and DefaultBaseURL.
+func NewServer(prefix string) *Server {
+	return NewServerWithBaseURL(prefix, DefaultBaseURL)
+}
+
+// NewServerWithBaseURL creates a Server which generates links with baseURL.
+func NewServerWithBaseURL(prefix, baseURL string) *Server {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	api := api2go.NewAPI(prefix, api2go.NewStaticResolver(baseURL))
+	return &Server{
+		Engine: e,
+		API:    api,
+		group:  e.Group(api.GetPrefix()),
+	}
+}
+
+// AddResource registers source for prototype, see api2go.API.AddResource.
+func (s *Server) AddResource(prototype api2go.Identifier,
+	source api2go.CRUD) *Server {
+	s.API.AddResource(s.group, prototype, source)
+	return s
+}
+
+// ServeHTTP lets Server be used as a plain http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Engine.ServeHTTP(w, r)
+}
+
+// NewRequest starts building a request with method on path. Failures of the
+// request and of every assertion on its response are reported to t.
+func (s *Server) NewRequest(t testing.TB, method, path string) *Request {
+	return &Request{
+		t:      t,
+		srv:    s,
+		method: method,
+		path:   path,
+		query:  url.Values{},
+		header: http.Header{
+			"Content-Type": []string{jsonapi.MediaType},
+			"Accept":       []string{jsonapi.MediaType},
+		},
+	}
+}
+
+// GET is a shortcut for NewRequest with GET.
+func (s *Server) GET(t testing.TB, path string) *Request {
+	return s.NewRequest(t, http.MethodGet, path)
+}
+
+// POST is a shortcut for NewRequest with POST.
+func (s *Server) POST(t testing.TB, path string) *Request {
+	return s.NewRequest(t, http.MethodPost, path)
+}
+
+// PATCH is a shortcut for NewRequest with PATCH.
+func (s *Server) PATCH(t testing.TB, path string) *Request {
+	return s.NewRequest(t, http.MethodPatch, path)
+}
+
+// DELETE is a shortcut for NewRequest with DELETE.
+func (s *Server) DELETE(t testing.TB, path string) *Request {
+	return s.NewRequest(t, http.MethodDelete, path)
+}
+
+// Request is a fluent builder for one request against a Server.
+type Request struct {
+	t      testing.TB
+	srv    *Server
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	body   io.Reader
+}
+
+// Query adds a query parameter, e.g. Query("fields[users]", "user-name").
+func (r *Request) Query(key, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+// Header sets a request header.
+func (r *Request) Header(key, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+// Body sets a raw request body.
+func (r *Request) Body(body string) *Request {
+	r.body = bytes.NewBufferString(body)
+	return r
+}
+
+// JSON sets the request body to the JSON encoding of v.
+func (r *Request) JSON(v interface{}) *Request {
+	b, err := json.Marshal(v)
+	if err != nil {
+		r.t.Fatalf("api2gotest: can not encode request body: %v", err)
+	}
+	r.body = bytes.NewBuffer(b)
+	return r
+}
+
+// Data sets the request body to a document with v as primary data, where v
+// is a model with jsonapi tags.
+func (r *Request) Data(v interface{}) *Request {
+	var buf bytes.Buffer
+	if err := jsonapi.MarshalPayload(&buf, v); err != nil {
+		r.t.Fatalf("api2gotest: can not marshal request data: %v", err)
+	}
+	r.body = &buf
+	return r
+}
+
+// Linkage sets the request body to a relationship document with resource
+// identifiers of typ and ids, as used by the relationship endpoints.
+func (r *Request) Linkage(typ string, ids ...string) *Request {
+	data := make([]map[string]string, 0, len(ids))
+	for _, id := range ids {
+		data = append(data, map[string]string{"type": typ, "id": id})
+	}
+	return r.JSON(map[string]interface{}{"data": data})
+}
+
+// Do serves the request and returns the recorded response.
+func (r *Request) Do() *Response {
+	r.t.Helper()
+	target := r.path
+	if len(r.query) > 0 {
+		target += "?" + r.query.Encode()
+	}
+	req := httptest.NewRequest(r.method, target, r.body)
+	for k, v := range r.header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	r.srv.ServeHTTP(rec, req)
+	return newResponse(r.t, r.method+" "+target, rec)
+}
@@ -66,6 +66,69 @@ type FindAll interface {
 	FindAll(req Request) (Responder, error)
 }
 
+// The FindMany interface can be optionally implemented to load many objects
+// by ID at once. It answers GET /v1/posts?filter[id]=1,2,3 unless other
+// filter, sort or page parameters are given, and loads the resources of the
+// include parameter with one call per type, instead of one FindOne per ID.
+type FindMany interface {
+	// FindMany returns the objects with ids as slice, missing ones are left
+	// out.
+	// Possible Responder success status code 200
+	FindMany(ids []string, req Request) (Responder, error)
+}
+
+// The Counter interface can be optionally implemented to answer HEAD on a
+// collection, e.g. HEAD /v1/posts, with the number of objects in the
+// TotalCountHeader instead of reading them with FindAll. The response has
+// no ETag and Content-Length then. The query parameters are in req.
+type Counter interface {
+	// Count returns the number of objects of the collection.
+	Count(req Request) (uint, error)
+}
+
+// The Exister interface can be optionally implemented to answer HEAD on a
+// resource, e.g. HEAD /v1/posts/1, with a cheap existence check instead of
+// FindOne. A missing object is answered with 404 Not Found. The response has
+// no ETag and Content-Length then, so If-None-Match is not answered with 304
+// Not Modified. Without it HEAD has the headers of GET.
+type Exister interface {
+	// Exists tells if the object with id exists.
+	Exists(id string, req Request) (bool, error)
+}
+
+// The RelationshipReplacer interface can be optionally implemented by a data
+// source to replace a relationship directly, e.g. with one UPDATE of a foreign
+// key or a rewrite of a join table, instead of FindOne, changing the object
+// and Update. It is called for
+//
+//	PATCH /v1/posts/1/relationships/comments
+//
+// with the ID of the post, the relationship name and the IDs of the linkage,
+// which are none or one for a to-one relationship.
+// Possible Responder status codes are the ones of Update for relationships:
+//   - 200 OK: The relationship was changed by the server beyond the request,
+//     the resulting linkage is read from the result or else from FindOne
+//   - 202 Accepted: Processing is delayed, return nothing
+//   - 204 No Content: The relationship was replaced, return nothing
+type RelationshipReplacer interface {
+	ReplaceRelationship(id, name string, ids []string, req Request) (Responder,
+		error)
+}
+
+// The RelationshipEditor interface can be optionally implemented by a data
+// source to add to and remove from to-many relationships directly, e.g. with
+// one INSERT or DELETE on a join table. AddToRelationship is called for POST
+// and RemoveFromRelationship for DELETE on /v1/posts/1/relationships/comments,
+// with the same arguments and Responder status codes as ReplaceRelationship.
+// IDs which are already in the relationship must not be added again, IDs
+// which are not in it are ignored on removal.
+type RelationshipEditor interface {
+	AddToRelationship(id, name string, ids []string, req Request) (Responder,
+		error)
+	RemoveFromRelationship(id, name string, ids []string, req Request) (
+		Responder, error)
+}
+
 // The ObjectInitializer interface can be implemented to have the ability to
 // change a created object before Unmarshal is called. This is currently only
 // called on Create as the other actions go through FindOne or FindAll which are
@@ -1,6 +1,7 @@
 package api2go
 
 import (
+	"log"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -11,6 +12,33 @@ import (
 // trail slash.
 type API struct {
 	ContentType string
+	// Logger receives handler errors and recovered panics. The standard logger
+	// is used when it is nil.
+	Logger *log.Logger
+	// PanicHandler is optional and called for every recovered panic.
+	PanicHandler PanicHandler
+	// Instrumentation is optional and observes every handled request.
+	Instrumentation Instrumentation
+	// Tracer is optional and traces every request and its phases.
+	Tracer Tracer
+	// MaxBodySize limits request bodies to this many bytes, larger ones are
+	// answered with 413 Request Entity Too Large. 0 sets no limit, see
+	// DefaultMaxBodySize for a common one. A data source can set the limit
+	// of its resource with BodySizeLimiter.
+	MaxBodySize int64
+	// ReferentialIntegrity makes the API check that the resources linked by
+	// create and update requests and by the relationship routes exist,
+	// before the data source is called. Linked types must be added to the
+	// API to be checked, by ExistenceChecker or else FindOne of their data
+	// source.
+	ReferentialIntegrity bool
+	// MissingLinkageStatus is the status answered for linkage to missing
+	// resources, 404 Not Found if 0. 422 Unprocessable Entity is common as
+	// well.
+	MissingLinkageStatus int
+	// Codec encodes responses and decodes relationship request bodies,
+	// StdCodec if nil.
+	Codec Codec
 	*information
 	resources []resource
 }
@@ -22,7 +50,14 @@ type API struct {
 // used for constructing new elements.
 func (api *API) AddResource(rg *gin.RouterGroup, prototype Identifier,
 	source CRUD) {
-	api.addResource(rg, prototype, source)
+	api.addResource(GinRouter(rg), prototype, source)
+}
+
+// AddResourceWithRouter is AddResource for any Router, e.g. an HTTPRouter
+// to mount the API on net/http.
+func (api *API) AddResourceWithRouter(router Router, prototype Identifier,
+	source CRUD) {
+	api.addResource(router, prototype, source)
 }
 
 // NewAPIWithResolver can be used to create an API with a custom URL resolver.
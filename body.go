@@ -0,0 +1,106 @@
+package api2go
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// DefaultMaxBodySize is a request body size limit in bytes which suits most
+// APIs, e.g. for API.MaxBodySize. Bodies are not limited unless the API or
+// the resource sets a limit.
+const DefaultMaxBodySize = 1 << 20
+
+// The BodySizeLimiter interface can be optionally implemented by a data
+// source to set the request body size limit of its resource, overriding
+// API.MaxBodySize.
+type BodySizeLimiter interface {
+	// MaxBodySize returns the limit in bytes, 0 to use the one of the API
+	// and a negative number for no limit.
+	MaxBodySize() int64
+}
+
+// maxBodySize returns the request body size limit of the resource, a
+// negative number if there is none.
+func (res *resource) maxBodySize() int64 {
+	if res.bodyLimiter != nil {
+		if limit := res.bodyLimiter.MaxBodySize(); limit != 0 {
+			return limit
+		}
+	}
+	if res.api.MaxBodySize > 0 {
+		return res.api.MaxBodySize
+	}
+	return -1
+}
+
+// tooLarge returns the 413 Request Entity Too Large error for limit.
+func tooLarge(limit int64) error {
+	return NewHTTPError(nil, fmt.Sprintf(
+		"The request body exceeds the limit of %d bytes", limit),
+		http.StatusRequestEntityTooLarge)
+}
+
+// body returns the request body, which fails with an *http.MaxBytesError
+// when it exceeds the limit of the resource.
+func (res *resource) body(c *routeContext) (io.ReadCloser, error) {
+	limit := res.maxBodySize()
+	if limit < 0 {
+		return c.Request.Body, nil
+	}
+	if c.Request.ContentLength > limit {
+		return nil, tooLarge(limit)
+	}
+	return http.MaxBytesReader(c.Writer, c.Request.Body, limit), nil
+}
+
+// readBody reads the whole request body within the limit of the resource.
+func (res *resource) readBody(c *routeContext) ([]byte, error) {
+	body, err := res.body(c)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, bodyError(err)
+	}
+	return data, nil
+}
+
+// decodeBody decodes the JSON request body into v with the Codec of the API
+// while reading it, within the limit of the resource.
+func (res *resource) decodeBody(c *routeContext, v interface{}) error {
+	body, err := res.body(c)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	if err := res.api.codec().Decode(body, v); err != nil {
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			return tooLarge(tooBig.Limit)
+		}
+		// the errors of other codecs are unknown, so any is a malformed body
+		return malformed(err)
+	}
+	return nil
+}
+
+// bodyError maps an error reading the request body to the HTTPError
+// answered.
+func bodyError(err error) error {
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) {
+		return tooLarge(tooBig.Limit)
+	}
+	var syntax *json.SyntaxError
+	var typ *json.UnmarshalTypeError
+	if errors.As(err, &syntax) || errors.As(err, &typ) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return malformed(err)
+	}
+	return err
+}
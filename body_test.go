@@ -0,0 +1,222 @@
+package api2go_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/cention-sany/api2go"
+)
+
+type book struct {
+	ID     string  `jsonapi:"primary,books"`
+	Title  string  `jsonapi:"attr,title"`
+	Author *author `jsonapi:"relation,author"`
+	Posts  []*post `jsonapi:"relation,posts"`
+}
+
+func (b book) GetID() string { return b.ID }
+
+func (b *book) SetToOneReferenceID(name, id string) error {
+	b.Author = nil
+	if id != "" {
+		b.Author = &author{ID: id}
+	}
+	return nil
+}
+
+func (b *book) SetToManyReferenceIDs(name string, ids []string) error {
+	b.Posts = nil
+	return b.AddToManyIDs(name, ids)
+}
+
+func (b *book) AddToManyIDs(name string, ids []string) error {
+	for _, id := range ids {
+		b.Posts = append(b.Posts, &post{ID: id})
+	}
+	return nil
+}
+
+func (b *book) DeleteToManyIDs(name string, ids []string) error {
+	return NewHTTPError(nil, "posts can not be removed", http.StatusForbidden)
+}
+
+func (b *book) postIDs() []string {
+	ids := []string{}
+	for _, p := range b.Posts {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+type bookSource struct {
+	panickySource
+	book  *book
+	limit int64
+	code  int
+	err   error
+}
+
+func (s *bookSource) MaxBodySize() int64 { return s.limit }
+
+func (s *bookSource) FindOne(id string, req Request) (Responder, error) {
+	b := *s.book
+	return &Response{Res: &b, Code: http.StatusOK}, nil
+}
+
+func (s *bookSource) Update(obj interface{}, req Request) (Responder, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.book = obj.(*book)
+	if s.code == 0 {
+		return &Response{Code: http.StatusNoContent}, nil
+	}
+	return &Response{Res: s.book, Code: s.code}, nil
+}
+
+func TestBodySizeLimit(t *testing.T) {
+	router := NewHTTPRouter("/v1")
+	api := NewAPI("v1", NewStaticResolver(""))
+	api.MaxBodySize = 64
+	api.AddResourceWithRouter(router, &post{}, newPostSource())
+	source := &bookSource{book: &book{ID: "1"}}
+	api.AddResourceWithRouter(router, &book{}, source)
+
+	long := `{"data":{"type":"posts","attributes":{"title":"` +
+		strings.Repeat("a", 64) + `"}}}`
+	if rec := serve(router, "POST", "/v1/posts", long); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expect status %d but got %d.",
+			http.StatusRequestEntityTooLarge, rec.Code)
+	}
+
+	// without a Content-Length the limit is hit while decoding
+	rec := serveChunked(router, "PATCH", "/v1/books/1/relationships/posts",
+		`{"data":[`+strings.Repeat(`{"type":"posts","id":"1"},`, 8)+`]}`)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expect status %d but got %d.",
+			http.StatusRequestEntityTooLarge, rec.Code)
+	}
+
+	source.limit = -1
+	rec = serveChunked(router, "PATCH", "/v1/books/1/relationships/posts",
+		`{"data":[`+strings.Repeat(`{"type":"posts","id":"1"},`, 8)+
+			`{"type":"posts","id":"2"}]}`)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expect status %d without a resource limit but got %d: %s",
+			http.StatusNoContent, rec.Code, rec.Body)
+	}
+	if len(source.book.Posts) != 9 {
+		t.Errorf("Expect 9 posts but got %v.", source.book.postIDs())
+	}
+}
+
+func TestNoBodySizeLimit(t *testing.T) {
+	router := NewHTTPRouter("/v1")
+	api := NewAPI("v1", NewStaticResolver(""))
+	api.AddResourceWithRouter(router, &post{}, newPostSource())
+
+	long := `{"data":{"type":"posts","attributes":{"title":"` +
+		strings.Repeat("a", 2*DefaultMaxBodySize) + `"}}}`
+	if rec := serve(router, "POST", "/v1/posts", long); rec.Code ==
+		http.StatusRequestEntityTooLarge {
+		t.Errorf("Expect no limit without API.MaxBodySize but got %d.",
+			rec.Code)
+	}
+}
+
+func TestRelationshipDecode(t *testing.T) {
+	router := NewHTTPRouter("/v1")
+	api := NewAPI("v1", NewStaticResolver(""))
+	source := &bookSource{book: &book{ID: "1"}}
+	api.AddResourceWithRouter(router, &book{}, source)
+
+	rec := serve(router, "PATCH", "/v1/books/1/relationships/author",
+		`{"data":{"type":"authors","id":"7"}}`)
+	if rec.Code != http.StatusNoContent || source.book.Author == nil ||
+		source.book.Author.ID != "7" {
+		t.Errorf("Expect author 7 but got %d %v.", rec.Code, source.book.Author)
+	}
+	rec = serve(router, "PATCH", "/v1/books/1/relationships/author",
+		`{"data":null}`)
+	if rec.Code != http.StatusNoContent || source.book.Author != nil {
+		t.Errorf("Expect no author but got %d %v.", rec.Code,
+			source.book.Author)
+	}
+	rec = serve(router, "POST", "/v1/books/1/relationships/posts",
+		`{"data":[{"type":"posts","id":"3"},{"type":"posts","id":"4"}]}`)
+	if exp := []string{"3", "4"}; rec.Code != http.StatusNoContent ||
+		!reflect.DeepEqual(exp, source.book.postIDs()) {
+		t.Errorf("Expect posts %v but got %d %v.", exp, rec.Code,
+			source.book.postIDs())
+	}
+
+	tests := []struct {
+		method, target, body string
+		code                 int
+	}{
+		{"POST", "/v1/books/1/relationships/posts",
+			`{"data":{"type":"posts","id":"3"}}`, http.StatusBadRequest},
+		{"PATCH", "/v1/books/1/relationships/posts",
+			`{"data":[{"type":"posts"}]}`, http.StatusBadRequest},
+		{"PATCH", "/v1/books/1/relationships/posts", `{"links":{}}`,
+			http.StatusBadRequest},
+		{"PATCH", "/v1/books/1/relationships/posts", `{"data":"1"}`,
+			http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := serve(router, tt.method, tt.target, tt.body); rec.Code != tt.code {
+			t.Errorf("Expect status %d for %s but got %d: %s", tt.code,
+				tt.body, rec.Code, rec.Body)
+		}
+	}
+}
+
+// serveChunked serves a request without a Content-Length.
+func serveChunked(h http.Handler, method, target,
+	body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, target, strings.NewReader(body))
+	req.ContentLength = -1
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRelationshipResponses(t *testing.T) {
+	router := NewHTTPRouter("/v1")
+	api := NewAPI("v1", NewStaticResolver(""))
+	source := &bookSource{book: &book{ID: "1"}}
+	api.AddResourceWithRouter(router, &book{}, source)
+
+	source.code = http.StatusOK
+	rec := serve(router, "POST", "/v1/books/1/relationships/posts",
+		`{"data":[{"type":"posts","id":"3"}]}`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(),
+		`{"data":[{"type":"posts","id":"3"}]}`) {
+		t.Errorf("Expect the resulting linkage but got %d: %s", rec.Code,
+			rec.Body)
+	}
+	source.code = http.StatusAccepted
+	rec = serve(router, "PATCH", "/v1/books/1/relationships/posts",
+		`{"data":[]}`)
+	if rec.Code != http.StatusAccepted || rec.Body.Len() != 0 {
+		t.Errorf("Expect status %d without body but got %d: %s",
+			http.StatusAccepted, rec.Code, rec.Body)
+	}
+
+	rec = serve(router, "DELETE", "/v1/books/1/relationships/posts",
+		`{"data":[{"type":"posts","id":"3"}]}`)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expect the status %d of DeleteToManyIDs but got %d.",
+			http.StatusForbidden, rec.Code)
+	}
+	source.err = NewHTTPError(nil, "locked", http.StatusLocked)
+	rec = serve(router, "PATCH", "/v1/books/1/relationships/author",
+		`{"data":null}`)
+	if rec.Code != http.StatusLocked {
+		t.Errorf("Expect the status %d of Update but got %d.",
+			http.StatusLocked, rec.Code)
+	}
+}
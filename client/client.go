@@ -0,0 +1,395 @@
+// Package client is a typed Go client for APIs served by api2go. It works
+// with the same model structs and jsonapi tags as the server:
+//
+//	c := client.New("https://api.example.com/v1")
+//	var user model.User
+//	err := c.FindOne(ctx, "1", &user, nil)
+//
+//	var users []*model.User
+//	q := client.Query{Sort: []string{"-created"}, Include: []string{"sweets"}}
+//	it := c.Iterate(ctx, &users, &q)
+//	for it.Next() {
+//		// users holds the current page
+//	}
+//	err = it.Err()
+//
+// Error documents are returned as *Error.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/cention-sany/jsonapi"
+)
+
+// Client talks to one api2go API.
+type Client struct {
+	base *url.URL
+	// HTTPClient sends all requests, http.DefaultClient if nil.
+	HTTPClient *http.Client
+	// Header is added to every request, e.g. for authorization.
+	Header http.Header
+}
+
+// New creates a client for the API at baseURL including its prefix, e.g.
+// "https://api.example.com/v1". It panics if baseURL can not be parsed.
+func New(baseURL string) *Client {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/") + "/")
+	if err != nil {
+		panic(fmt.Sprint("client: invalid base url: ", err))
+	}
+	return &Client{base: u, Header: http.Header{}}
+}
+
+// Identifier is a resource identifier object as used by the relationship
+// endpoints.
+type Identifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// resolve turns a path relative to the base URL or a link href into an
+// absolute URL.
+func (c *Client) resolve(ref string) (string, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	return c.base.ResolveReference(u).String(), nil
+}
+
+// do sends a request and returns the response body of a successful request.
+// Error documents are turned into *Error.
+func (c *Client) do(ctx context.Context, method, ref string, q *Query,
+	body io.Reader) (int, []byte, error) {
+	target, err := c.resolve(ref)
+	if err != nil {
+		return 0, nil, err
+	}
+	if q != nil {
+		if v := q.Values().Encode(); v != "" {
+			target += "?" + v
+		}
+	}
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", jsonapi.MediaType)
+	if body != nil {
+		req.Header.Set("Content-Type", jsonapi.MediaType)
+	}
+	rsp, err := c.httpClient().Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rsp.Body.Close()
+	b, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return rsp.StatusCode, nil, err
+	}
+	if rsp.StatusCode >= http.StatusBadRequest {
+		return rsp.StatusCode, nil, newError(rsp.StatusCode, b)
+	}
+	return rsp.StatusCode, b, nil
+}
+
+// typeName returns the resource name of the jsonapi primary tag of t.
+func typeName(t reflect.Type) (string, error) {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return "", fmt.Errorf("client: %s is not a model struct", t)
+	}
+	for i := 0; i < t.NumField(); i++ {
+		args := strings.Split(t.Field(i).Tag.Get("jsonapi"), ",")
+		if len(args) > 1 && args[0] == "primary" {
+			return args[1], nil
+		}
+	}
+	return "", fmt.Errorf("client: %s has no jsonapi primary tag", t)
+}
+
+// idOf returns the ID of a model pointer.
+func idOf(model interface{}) string {
+	if i, ok := model.(interface {
+		GetID() string
+	}); ok {
+		return i.GetID()
+	}
+	return ""
+}
+
+func checkPtr(model interface{}) error {
+	v := reflect.ValueOf(model)
+	if v.Kind() != reflect.Ptr || v.IsNil() ||
+		v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("client: model must be a struct pointer, got %T",
+			model)
+	}
+	return nil
+}
+
+// FindOne loads the resource with id into model, a pointer to a model
+// struct. q may be nil.
+func (c *Client) FindOne(ctx context.Context, id string, model interface{},
+	q *Query) error {
+	if err := checkPtr(model); err != nil {
+		return err
+	}
+	name, err := typeName(reflect.TypeOf(model))
+	if err != nil {
+		return err
+	}
+	_, b, err := c.do(ctx, http.MethodGet, name+"/"+url.PathEscape(id),
+		q, nil)
+	if err != nil {
+		return err
+	}
+	return jsonapi.UnmarshalPayload(bytes.NewReader(b), model)
+}
+
+// Page holds the top-level links and meta of a collection response.
+type Page struct {
+	Links map[string]string
+	Meta  map[string]interface{}
+}
+
+// Next returns the href of the next link or "".
+func (p *Page) Next() string {
+	return p.Links["next"]
+}
+
+// decodeMany decodes a collection document b into list, a pointer to a slice
+// of model pointers.
+func decodeMany(b []byte, list interface{}) (*Page, error) {
+	lv := reflect.ValueOf(list)
+	if lv.Kind() != reflect.Ptr || lv.Elem().Kind() != reflect.Slice ||
+		lv.Elem().Type().Elem().Kind() != reflect.Ptr {
+		return nil, fmt.Errorf("client: list must be a pointer to a slice "+
+			"of model pointers, got %T", list)
+	}
+	var top struct {
+		Links map[string]json.RawMessage `json:"links"`
+		Meta  map[string]interface{}     `json:"meta"`
+	}
+	if err := json.Unmarshal(b, &top); err != nil {
+		return nil, err
+	}
+	page := &Page{Links: map[string]string{}, Meta: top.Meta}
+	for name, raw := range top.Links {
+		page.Links[name] = href(raw)
+	}
+	elem := lv.Elem().Type().Elem()
+	items, err := jsonapi.UnmarshalManyPayload(bytes.NewReader(b), elem)
+	if err != nil {
+		return nil, err
+	}
+	out := reflect.MakeSlice(lv.Elem().Type(), 0, len(items))
+	for _, item := range items {
+		out = reflect.Append(out, reflect.ValueOf(item))
+	}
+	lv.Elem().Set(out)
+	return page, nil
+}
+
+// href returns the URL of a link which is either a string or a link object.
+func href(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var l struct {
+		Href string `json:"href"`
+	}
+	json.Unmarshal(raw, &l)
+	return l.Href
+}
+
+// FindAll loads one page of the collection of the model type into list, a
+// pointer to a slice of model pointers. q may be nil.
+func (c *Client) FindAll(ctx context.Context, list interface{},
+	q *Query) (*Page, error) {
+	name, err := typeName(reflect.TypeOf(list))
+	if err != nil {
+		return nil, err
+	}
+	_, b, err := c.do(ctx, http.MethodGet, name, q, nil)
+	if err != nil {
+		return nil, err
+	}
+	return decodeMany(b, list)
+}
+
+// Related loads the resources linked by relationship name of the resource
+// typ/id into list, a pointer to a slice of model pointers.
+func (c *Client) Related(ctx context.Context, typ, id, name string,
+	list interface{}, q *Query) (*Page, error) {
+	_, b, err := c.do(ctx, http.MethodGet,
+		typ+"/"+url.PathEscape(id)+"/"+name, q, nil)
+	if err != nil {
+		return nil, err
+	}
+	return decodeMany(b, list)
+}
+
+func marshal(model interface{}) (io.Reader, error) {
+	var buf bytes.Buffer
+	if err := jsonapi.MarshalPayload(&buf, model); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
+// Create posts model, a pointer to a model struct. If the server returns the
+// created resource it is unmarshalled into model, so the generated ID is
+// available afterwards. The http status code is returned as well.
+func (c *Client) Create(ctx context.Context, model interface{}) (int, error) {
+	if err := checkPtr(model); err != nil {
+		return 0, err
+	}
+	name, err := typeName(reflect.TypeOf(model))
+	if err != nil {
+		return 0, err
+	}
+	body, err := marshal(model)
+	if err != nil {
+		return 0, err
+	}
+	code, b, err := c.do(ctx, http.MethodPost, name, nil, body)
+	if err != nil || len(b) == 0 {
+		return code, err
+	}
+	return code, jsonapi.UnmarshalPayload(bytes.NewReader(b), model)
+}
+
+// Update patches the resource of model with all its attributes and
+// relationships. If the server returns the updated resource it is
+// unmarshalled into model. The http status code is returned as well.
+func (c *Client) Update(ctx context.Context, model interface{}) (int, error) {
+	if err := checkPtr(model); err != nil {
+		return 0, err
+	}
+	name, err := typeName(reflect.TypeOf(model))
+	if err != nil {
+		return 0, err
+	}
+	id := idOf(model)
+	if id == "" {
+		return 0, errors.New("client: can not update a model without ID")
+	}
+	body, err := marshal(model)
+	if err != nil {
+		return 0, err
+	}
+	code, b, err := c.do(ctx, http.MethodPatch, name+"/"+url.PathEscape(id),
+		nil, body)
+	if err != nil || len(b) == 0 {
+		return code, err
+	}
+	return code, jsonapi.UnmarshalPayload(bytes.NewReader(b), model)
+}
+
+// Delete deletes the resource typ/id and returns the http status code.
+func (c *Client) Delete(ctx context.Context, typ, id string) (int, error) {
+	code, _, err := c.do(ctx, http.MethodDelete,
+		typ+"/"+url.PathEscape(id), nil, nil)
+	return code, err
+}
+
+func relationshipPath(typ, id, name string) string {
+	return typ + "/" + url.PathEscape(id) + "/relationships/" + name
+}
+
+// Relationship returns the linkage of relationship name of the resource
+// typ/id. A to-one relationship returns at most one Identifier.
+func (c *Client) Relationship(ctx context.Context, typ, id,
+	name string) ([]Identifier, error) {
+	_, b, err := c.do(ctx, http.MethodGet, relationshipPath(typ, id, name),
+		nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	data := bytes.TrimSpace(doc.Data)
+	if len(data) == 0 || string(data) == "null" {
+		return []Identifier{}, nil
+	}
+	if data[0] == '[' {
+		var many []Identifier
+		err = json.Unmarshal(data, &many)
+		return many, err
+	}
+	var one Identifier
+	err = json.Unmarshal(data, &one)
+	return []Identifier{one}, err
+}
+
+func (c *Client) editRelationship(ctx context.Context, method, typ, id,
+	name string, data interface{}) (int, error) {
+	b, err := json.Marshal(map[string]interface{}{"data": data})
+	if err != nil {
+		return 0, err
+	}
+	code, _, err := c.do(ctx, method, relationshipPath(typ, id, name), nil,
+		bytes.NewReader(b))
+	return code, err
+}
+
+// ReplaceRelationship replaces the whole to-many linkage of relationship
+// name of the resource typ/id.
+func (c *Client) ReplaceRelationship(ctx context.Context, typ, id,
+	name string, linkage []Identifier) (int, error) {
+	if linkage == nil {
+		linkage = []Identifier{}
+	}
+	return c.editRelationship(ctx, http.MethodPatch, typ, id, name, linkage)
+}
+
+// SetToOneRelationship replaces a to-one relationship, a nil linkage clears
+// it.
+func (c *Client) SetToOneRelationship(ctx context.Context, typ, id,
+	name string, linkage *Identifier) (int, error) {
+	return c.editRelationship(ctx, http.MethodPatch, typ, id, name, linkage)
+}
+
+// AddToRelationship adds linkage to the to-many relationship name of the
+// resource typ/id.
+func (c *Client) AddToRelationship(ctx context.Context, typ, id,
+	name string, linkage []Identifier) (int, error) {
+	return c.editRelationship(ctx, http.MethodPost, typ, id, name, linkage)
+}
+
+// RemoveFromRelationship removes linkage from the to-many relationship name
+// of the resource typ/id.
+func (c *Client) RemoveFromRelationship(ctx context.Context, typ, id,
+	name string, linkage []Identifier) (int, error) {
+	return c.editRelationship(ctx, http.MethodDelete, typ, id, name, linkage)
+}
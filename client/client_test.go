@@ -0,0 +1,168 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/cention-sany/api2go"
+	"github.com/cention-sany/api2go/api2gotest"
+	"github.com/cention-sany/api2go/client"
+)
+
+type task struct {
+	ID   string `jsonapi:"primary,tasks"`
+	Name string `jsonapi:"attr,name"`
+}
+
+func (t task) GetID() string { return t.ID }
+
+type tasks struct {
+	list []*task
+}
+
+func (s *tasks) FindAll(req api2go.Request) (api2go.Responder, error) {
+	return &api2go.Response{Res: s.list, Code: http.StatusOK}, nil
+}
+
+func (s *tasks) PaginatedFindAll(req api2go.Request) (uint,
+	api2go.Responder, error) {
+	_, offset, limit, err := api2go.OffsetPage(&req)
+	if err != nil {
+		return 0, nil, err
+	}
+	end := offset + limit
+	if end > len(s.list) {
+		end = len(s.list)
+	}
+	return uint(len(s.list)), &api2go.Response{Res: s.list[offset:end],
+		Code: http.StatusOK}, nil
+}
+
+func (s *tasks) FindOne(id string, req api2go.Request) (api2go.Responder,
+	error) {
+	for _, t := range s.list {
+		if t.ID == id {
+			return &api2go.Response{Res: t, Code: http.StatusOK}, nil
+		}
+	}
+	return nil, api2go.NewHTTPError(nil, "Task not found", http.StatusNotFound)
+}
+
+func (s *tasks) Create(obj interface{}, req api2go.Request) (api2go.Responder,
+	error) {
+	t := obj.(*task)
+	t.ID = strconv.Itoa(len(s.list) + 1)
+	s.list = append(s.list, t)
+	return &api2go.Response{Res: t, Code: http.StatusCreated}, nil
+}
+
+func (s *tasks) Delete(id string, req api2go.Request) (api2go.Responder,
+	error) {
+	for i, t := range s.list {
+		if t.ID == id {
+			s.list = append(s.list[:i], s.list[i+1:]...)
+			break
+		}
+	}
+	return &api2go.Response{Code: http.StatusNoContent}, nil
+}
+
+func (s *tasks) Update(obj interface{}, req api2go.Request) (api2go.Responder,
+	error) {
+	t := obj.(*task)
+	for i := range s.list {
+		if s.list[i].ID == t.ID {
+			s.list[i] = t
+		}
+	}
+	return &api2go.Response{Res: t, Code: http.StatusOK}, nil
+}
+
+func newClient(t *testing.T) *client.Client {
+	srv := api2gotest.NewServerWithBaseURL("v1", "").
+		AddResource(&task{}, &tasks{})
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	return client.New(ts.URL + "/v1")
+}
+
+func TestCRUD(t *testing.T) {
+	ctx := context.Background()
+	c := newClient(t)
+	for _, name := range []string{"write", "test", "ship"} {
+		tk := &task{Name: name}
+		code, err := c.Create(ctx, tk)
+		if err != nil || code != http.StatusCreated {
+			t.Fatalf("Create: %d %v", code, err)
+		}
+		if tk.ID == "" {
+			t.Fatal("Expect created task to get an ID.")
+		}
+	}
+
+	var tk task
+	if err := c.FindOne(ctx, "2", &tk, nil); err != nil {
+		t.Fatal(err)
+	}
+	if tk.Name != "test" {
+		t.Errorf("Expect task test but got %q.", tk.Name)
+	}
+
+	tk.Name = "test more"
+	if _, err := c.Update(ctx, &tk); err != nil {
+		t.Fatal(err)
+	}
+	var all []*task
+	if _, err := c.FindAll(ctx, &all, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 || all[1].Name != "test more" {
+		t.Errorf("Expect 3 tasks with the update but got %v.", all)
+	}
+
+	if _, err := c.Delete(ctx, "tasks", "2"); err != nil {
+		t.Fatal(err)
+	}
+	err := c.FindOne(ctx, "2", &tk, nil)
+	e, ok := err.(*client.Error)
+	if !ok || e.Status() != http.StatusNotFound {
+		t.Fatalf("Expect a 404 *client.Error but got %v.", err)
+	}
+	if len(e.Errors) != 1 || e.Errors[0].Title != "Task not found" {
+		t.Errorf("Expect the decoded error object but got %+v.", e.Errors)
+	}
+}
+
+func TestIterate(t *testing.T) {
+	ctx := context.Background()
+	c := newClient(t)
+	for i := 0; i < 5; i++ {
+		if _, err := c.Create(ctx, &task{Name: strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var (
+		page  []*task
+		names []string
+		pages int
+	)
+	it := c.Iterate(ctx, &page, &client.Query{
+		Page: map[string]string{"offset": "0", "limit": "2"},
+	})
+	for it.Next() {
+		pages++
+		for _, tk := range page {
+			names = append(names, tk.Name)
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if pages != 3 || len(names) != 5 || names[4] != "4" {
+		t.Errorf("Expect 5 tasks on 3 pages but got %v on %d pages.", names,
+			pages)
+	}
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// ErrorSource is the source member of an ErrorObject.
+type ErrorSource struct {
+	Pointer   string `json:"pointer,omitempty"`
+	Parameter string `json:"parameter,omitempty"`
+}
+
+// ErrorObject is one entry of an error document.
+type ErrorObject struct {
+	ID     string                 `json:"id,omitempty"`
+	Status string                 `json:"status,omitempty"`
+	Code   string                 `json:"code,omitempty"`
+	Title  string                 `json:"title,omitempty"`
+	Detail string                 `json:"detail,omitempty"`
+	Source *ErrorSource           `json:"source,omitempty"`
+	Meta   map[string]interface{} `json:"meta,omitempty"`
+}
+
+// Error is returned for every response with a status code of 400 or above.
+// It mirrors api2go.HTTPError on the client side.
+type Error struct {
+	// StatusCode is the http status code of the response.
+	StatusCode int
+	// Errors holds the decoded error objects, it is empty if the body was
+	// not an error document.
+	Errors []ErrorObject
+	// Body is the raw response body.
+	Body []byte
+}
+
+func newError(status int, body []byte) *Error {
+	e := &Error{StatusCode: status, Body: body}
+	var doc struct {
+		Errors []ErrorObject `json:"errors"`
+	}
+	if json.Unmarshal(body, &doc) == nil {
+		e.Errors = doc.Errors
+	}
+	return e
+}
+
+// Status returns the http status code, like api2go.HTTPError.
+func (e *Error) Status() int {
+	return e.StatusCode
+}
+
+// Error returns a nice string represenation including the status
+func (e *Error) Error() string {
+	msg := http.StatusText(e.StatusCode)
+	if len(e.Errors) > 0 {
+		first := e.Errors[0]
+		if first.Title != "" {
+			msg = first.Title
+		}
+		if first.Detail != "" {
+			msg += ": " + first.Detail
+		}
+	}
+	more := 0
+	if len(e.Errors) > 1 {
+		more = len(e.Errors) - 1
+	}
+	return fmt.Sprintf("http error (%d) %s and %d more errors", e.StatusCode,
+		msg, more)
+}
+
+// HasCode tells if one of the error objects has the application code.
+func (e *Error) HasCode(code string) bool {
+	for _, o := range e.Errors {
+		if o.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// HasStatus tells if the response or one of the error objects has status.
+func (e *Error) HasStatus(status int) bool {
+	if e.StatusCode == status {
+		return true
+	}
+	s := strconv.Itoa(status)
+	for _, o := range e.Errors {
+		if o.Status == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+)
+
+// Iterator walks all pages of a collection by following the next links of
+// the responses. Each call of Next loads one page into the list given to
+// Iterate.
+type Iterator struct {
+	c     *Client
+	ctx   context.Context
+	list  interface{}
+	ref   string
+	query *Query
+	page  *Page
+	done  bool
+	err   error
+}
+
+// Iterate returns an Iterator over the collection of the model type of list,
+// a pointer to a slice of model pointers. q may be nil, set q.Page to choose
+// the page size.
+func (c *Client) Iterate(ctx context.Context, list interface{},
+	q *Query) *Iterator {
+	it := &Iterator{c: c, ctx: ctx, list: list, query: q}
+	it.ref, it.err = typeName(reflect.TypeOf(list))
+	return it
+}
+
+// Next loads the next page. It returns false when there are no more pages or
+// an error occurred, see Err.
+func (it *Iterator) Next() bool {
+	if it.done || it.err != nil {
+		return false
+	}
+	_, b, err := it.c.do(it.ctx, http.MethodGet, it.ref, it.query, nil)
+	if err != nil {
+		it.err = err
+		return false
+	}
+	page, err := decodeMany(b, it.list)
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.page = page
+	if next := page.Next(); next != "" {
+		// the next link carries the whole query already
+		it.ref, it.query = next, nil
+	} else {
+		it.done = true
+	}
+	return true
+}
+
+// Page returns the links and meta of the current page.
+func (it *Iterator) Page() *Page {
+	return it.page
+}
+
+// Err returns the first error that stopped the iteration.
+func (it *Iterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Query holds the query parameters of a read request.
+type Query struct {
+	// Sort lists sort fields, prefixed with "-" for descending order.
+	Sort []string
+	// Filter is sent as filter[key]=value.
+	Filter map[string]string
+	// Include lists relationship paths to include.
+	Include []string
+	// Fields is sent as fields[type]=a,b to request sparse fieldsets.
+	Fields map[string][]string
+	// Page is sent as page[key]=value, e.g. "number" and "size".
+	Page map[string]string
+	// Params holds any other query parameter.
+	Params url.Values
+}
+
+// Values returns the encoded query parameters.
+func (q *Query) Values() url.Values {
+	v := url.Values{}
+	if q == nil {
+		return v
+	}
+	for k, vs := range q.Params {
+		v[k] = append([]string{}, vs...)
+	}
+	if len(q.Sort) > 0 {
+		v.Set("sort", strings.Join(q.Sort, ","))
+	}
+	if len(q.Include) > 0 {
+		v.Set("include", strings.Join(q.Include, ","))
+	}
+	for k, f := range q.Filter {
+		v.Set("filter["+k+"]", f)
+	}
+	for typ, fields := range q.Fields {
+		v.Set("fields["+typ+"]", strings.Join(fields, ","))
+	}
+	for k, p := range q.Page {
+		v.Set("page["+k+"]", p)
+	}
+	return v
+}
@@ -0,0 +1,791 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Stores of the resource skeleton.
+const (
+	storeNone = ""
+	storeMem  = "memstore"
+	storeSQL  = "sqlstore"
+)
+
+// model is a struct type to generate the methods of.
+type model struct {
+	Name     string
+	Receiver string
+	IDField  string
+	IDType   string // the kind of the ID field: string, int or uint
+	IDCast   string // the type of the ID field
+	Type     string // the primary type
+	Attrs    []attribute
+	ToOne    []relation
+	ToMany   []relation
+	// Skip holds the methods which the package declares already.
+	Skip map[string]bool
+}
+
+// relation is a relationship field of a model.
+type relation struct {
+	Name  string // the name of the relationship
+	Field string
+	Elem  string // the type of a related object, without *
+	Ptr   bool   // if related objects are pointers
+	Type  string // the primary type of related objects
+	// OmitEmpty leaves the relationship out of resource objects if it is
+	// empty.
+	OmitEmpty bool
+}
+
+// attribute is an attribute field of a model.
+type attribute struct {
+	Name  string
+	Field string
+	// Time is "unix" or "iso8601" for a time.Time field, "" else.
+	Time string
+	// NonEmpty is the condition of an omitempty attribute to be marshalled,
+	// with %s for the field, "" if it is always marshalled.
+	NonEmpty string
+}
+
+// Cond returns the omitempty condition of a on the field of receiver.
+func (a attribute) Cond(receiver string) string {
+	return fmt.Sprintf(a.NonEmpty, receiver+"."+a.Field)
+}
+
+// Generate tells if the method is to be written, which it is unless the
+// package declares it already.
+func (m model) Generate(method string) bool {
+	return !m.Skip[method]
+}
+
+// Elements returns the type of the slice elements of r.
+func (r relation) Elements() string {
+	if r.Ptr {
+		return "*" + r.Elem
+	}
+	return r.Elem
+}
+
+// config is the generator input.
+type config struct {
+	dir    string   // the package directory
+	pkg    string   // the package name, read from the files if ""
+	types  []string // the names of the models
+	store  string   // storeNone, storeMem or storeSQL
+	node   bool     // whether to write MarshalJSONAPINode and UnmarshalJSONAPINode
+	output string   // the name of the generated file, which is not parsed
+}
+
+// generate reads the models of c from the package and returns the formatted
+// source of their methods.
+func generate(c config) ([]byte, error) {
+	files, pkg, err := parseDir(c.dir, c.output)
+	if err != nil {
+		return nil, err
+	}
+	if c.pkg != "" {
+		pkg = c.pkg
+	}
+	methods := declaredMethods(files)
+	primaries := primaryTypes(files)
+	models := make([]model, 0, len(c.types))
+	for _, name := range c.types {
+		st := findStruct(files, name)
+		if st == nil {
+			return nil, fmt.Errorf("api2gogen: struct type %s not found", name)
+		}
+		m, err := newModel(name, st, primaries)
+		if err != nil {
+			return nil, err
+		}
+		m.Skip = methods[name]
+		if !c.node {
+			if m.Skip == nil {
+				m.Skip = map[string]bool{}
+			}
+			m.Skip["MarshalJSONAPINode"] = true
+			m.Skip["UnmarshalJSONAPINode"] = true
+		}
+		models = append(models, m)
+	}
+	var buf bytes.Buffer
+	err = fileTemplate.Execute(&buf, struct {
+		Package string
+		Models  []model
+		Store   string
+		Imports []string
+	}{pkg, models, c.store, imports(models, c.store)})
+	if err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("api2gogen: invalid generated code: %v", err)
+	}
+	return src, nil
+}
+
+// parseDir parses the Go files of dir except tests and the file skip.
+func parseDir(dir, skip string) ([]*ast.File, string, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, "", err
+	}
+	fset := token.NewFileSet()
+	var (
+		files []*ast.File
+		pkg   string
+	)
+	for _, name := range names {
+		base := filepath.Base(name)
+		if base == skip || strings.HasSuffix(base, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(name)
+		if err != nil {
+			return nil, "", err
+		}
+		f, err := parser.ParseFile(fset, name, src, 0)
+		if err != nil {
+			return nil, "", err
+		}
+		pkg = f.Name.Name
+		files = append(files, f)
+	}
+	return files, pkg, nil
+}
+
+// declaredMethods returns the method names of every receiver type.
+func declaredMethods(files []*ast.File) map[string]map[string]bool {
+	methods := map[string]map[string]bool{}
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || len(fn.Recv.List) == 0 {
+				continue
+			}
+			t := fn.Recv.List[0].Type
+			if star, ok := t.(*ast.StarExpr); ok {
+				t = star.X
+			}
+			ident, ok := t.(*ast.Ident)
+			if !ok {
+				continue
+			}
+			if methods[ident.Name] == nil {
+				methods[ident.Name] = map[string]bool{}
+			}
+			methods[ident.Name][fn.Name.Name] = true
+		}
+	}
+	return methods
+}
+
+// findStruct returns the struct type declared as name.
+func findStruct(files []*ast.File, name string) *ast.StructType {
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if ts.Name.Name != name {
+					continue
+				}
+				if st, ok := ts.Type.(*ast.StructType); ok {
+					return st
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// primaryTypes returns the primary types of the struct types by name.
+func primaryTypes(files []*ast.File) map[string]string {
+	primaries := map[string]string{}
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok {
+					continue
+				}
+				for _, field := range st.Fields.List {
+					if args := tagArgs(field); len(args) > 1 &&
+						args[0] == "primary" {
+						primaries[ts.Name.Name] = args[1]
+					}
+				}
+			}
+		}
+	}
+	return primaries
+}
+
+// tagArgs returns the comma separated arguments of the jsonapi tag of field.
+func tagArgs(field *ast.Field) []string {
+	if field.Tag == nil {
+		return nil
+	}
+	tag := reflect.StructTag(strings.Trim(field.Tag.Value, "`")).Get("jsonapi")
+	return strings.Split(tag, ",")
+}
+
+// hasOption tells if the tag arguments args have option after the name.
+func hasOption(args []string, option string) bool {
+	for _, arg := range args[2:] {
+		if arg == option {
+			return true
+		}
+	}
+	return false
+}
+
+// nonEmpty returns the omitempty condition of an attribute of type t.
+func nonEmpty(t string) (string, bool) {
+	switch {
+	case t == "string":
+		return `%s != ""`, true
+	case t == "bool":
+		return "%s", true
+	case t == "time.Time":
+		return "!%s.IsZero()", true
+	case strings.HasPrefix(t, "[]") || strings.HasPrefix(t, "map["):
+		return "len(%s) > 0", true
+	case strings.HasPrefix(t, "*"):
+		return "%s != nil", true
+	}
+	switch strings.TrimRight(t, "0123456789") {
+	case "int", "uint", "float", "byte", "rune", "uintptr":
+		return "%s != 0", true
+	}
+	return "", false
+}
+
+// newModel reads the primary, attribute and relation fields of st from
+// their jsonapi tags. primaries holds the primary types of the package.
+func newModel(name string, st *ast.StructType,
+	primaries map[string]string) (model, error) {
+	m := model{Name: name, Receiver: strings.ToLower(name[:1])}
+	for _, field := range st.Fields.List {
+		args := tagArgs(field)
+		if len(field.Names) != 1 || len(args) < 2 {
+			continue
+		}
+		fieldName := field.Names[0].Name
+		switch args[0] {
+		case "primary":
+			m.Type = args[1]
+			m.IDField = fieldName
+			m.IDCast = types.ExprString(field.Type)
+			switch m.IDCast {
+			case "string":
+				m.IDType = "string"
+			case "int", "int8", "int16", "int32", "int64":
+				m.IDType = "int"
+			case "uint", "uint8", "uint16", "uint32", "uint64":
+				m.IDType = "uint"
+			default:
+				return m, fmt.Errorf("api2gogen: %s.%s must be a string or "+
+					"an integer", name, fieldName)
+			}
+		case "attr":
+			a := attribute{Name: args[1], Field: fieldName}
+			t := types.ExprString(field.Type)
+			if t == "time.Time" {
+				a.Time = "unix"
+				if hasOption(args, "iso8601") {
+					a.Time = "iso8601"
+				}
+			} else if strings.Contains(t, "time.Time") {
+				return m, fmt.Errorf("api2gogen: %s.%s of type %s is not "+
+					"supported", name, fieldName, t)
+			}
+			if hasOption(args, "omitempty") {
+				cond, ok := nonEmpty(t)
+				if !ok {
+					return m, fmt.Errorf("api2gogen: omitempty of %s.%s of "+
+						"type %s is not supported", name, fieldName, t)
+				}
+				a.NonEmpty = cond
+			}
+			m.Attrs = append(m.Attrs, a)
+		case "relation":
+			r := relation{Name: args[1], Field: fieldName,
+				OmitEmpty: hasOption(args, "omitempty")}
+			t := field.Type
+			isMany := false
+			if slice, ok := t.(*ast.ArrayType); ok && slice.Len == nil {
+				isMany, t = true, slice.Elt
+			}
+			if star, ok := t.(*ast.StarExpr); ok {
+				r.Ptr, t = true, star.X
+			}
+			r.Elem = types.ExprString(t)
+			r.Type = primaries[r.Elem]
+			if isMany {
+				m.ToMany = append(m.ToMany, r)
+			} else {
+				m.ToOne = append(m.ToOne, r)
+			}
+		}
+	}
+	if m.IDField == "" {
+		return m, fmt.Errorf("api2gogen: %s has no primary field", name)
+	}
+	if m.Generate("MarshalJSONAPINode") {
+		for _, r := range append(m.ToOne, m.ToMany...) {
+			if r.Type == "" {
+				return m, fmt.Errorf("api2gogen: the primary type of %s of "+
+					"%s.%s is unknown, it must be a struct of the package",
+					r.Elem, name, r.Field)
+			}
+		}
+	}
+	return m, nil
+}
+
+// imports returns the packages the generated file needs, the standard
+// library first.
+func imports(models []model, store string) []string {
+	set := map[string]bool{}
+	for _, m := range models {
+		if m.IDType != "string" && (m.Generate("GetID") ||
+			m.Generate("SetID")) {
+			set["strconv"] = true
+		}
+		if len(m.ToOne) > 0 && m.Generate("SetToOneReferenceID") ||
+			len(m.ToMany) > 0 && (m.Generate("SetToManyReferenceIDs") ||
+				m.Generate("AddToManyIDs") || m.Generate("DeleteToManyIDs")) {
+			set["errors"] = true
+		}
+		if m.Generate("MarshalJSONAPINode") ||
+			m.Generate("UnmarshalJSONAPINode") {
+			set["github.com/cention-sany/jsonapi"] = true
+		}
+		for _, a := range m.Attrs {
+			// only the unmarshalling of unix timestamps names package time
+			if a.Time == "unix" && m.Generate("UnmarshalJSONAPINode") {
+				set["time"] = true
+			}
+			if m.Generate("UnmarshalJSONAPINode") {
+				set["github.com/cention-sany/api2go"] = true
+			}
+		}
+	}
+	switch store {
+	case storeMem:
+		set["context"] = true
+		set["github.com/cention-sany/api2go"] = true
+		set["github.com/cention-sany/api2go/memstore"] = true
+	case storeSQL:
+		set["context"] = true
+		set["database/sql"] = true
+		set["github.com/cention-sany/api2go"] = true
+		set["github.com/cention-sany/api2go/sqlstore"] = true
+	}
+	var std, other []string
+	for p := range set {
+		if strings.Contains(p, ".") {
+			other = append(other, p)
+		} else {
+			std = append(std, p)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+	if len(std) > 0 && len(other) > 0 {
+		// an empty path separates the groups
+		std = append(std, "")
+	}
+	return append(std, other...)
+}
+
+var fileTemplate = newTemplate()
+
+// fileSrc is the template of the generated file.
+const fileSrc = `
+// Code generated by api2gogen. DO NOT EDIT.
+
+package {{.Package}}
+
+{{if .Imports}}
+import (
+{{- range .Imports}}
+{{- if eq . ""}}
+{{else}}
+	"{{.}}"
+{{- end}}
+{{- end}}
+)
+{{end}}
+{{range $m := .Models}}
+{{- if $m.Generate "GetID"}}
+// GetID implements api2go.Identifier.
+func ({{$m.Receiver}} {{$m.Name}}) GetID() string {
+{{- if eq $m.IDType "string"}}
+	return {{$m.Receiver}}.{{$m.IDField}}
+{{- else if eq $m.IDType "int"}}
+	return strconv.FormatInt(int64({{$m.Receiver}}.{{$m.IDField}}), 10)
+{{- else}}
+	return strconv.FormatUint(uint64({{$m.Receiver}}.{{$m.IDField}}), 10)
+{{- end}}
+}
+{{end}}
+{{- if $m.Generate "SetID"}}
+// SetID implements api2go.UnmarshalIdentifier.
+func ({{$m.Receiver}} *{{$m.Name}}) SetID(id string) error {
+{{- if eq $m.IDType "string"}}
+	{{$m.Receiver}}.{{$m.IDField}} = id
+{{- else}}
+	if id == "" {
+		{{$m.Receiver}}.{{$m.IDField}} = 0
+		return nil
+	}
+{{- if eq $m.IDType "int"}}
+	n, err := strconv.ParseInt(id, 10, 64)
+{{- else}}
+	n, err := strconv.ParseUint(id, 10, 64)
+{{- end}}
+	if err != nil {
+		return err
+	}
+	{{$m.Receiver}}.{{$m.IDField}} = {{$m.IDCast}}(n)
+{{- end}}
+	return nil
+}
+{{end}}
+{{- if and $m.ToOne ($m.Generate "SetToOneReferenceID")}}
+// SetToOneReferenceID implements api2go.UnmarshalToOneRelations. An empty id
+// removes the related object.
+func ({{$m.Receiver}} *{{$m.Name}}) SetToOneReferenceID(name, id string) error {
+	switch name {
+{{- range $m.ToOne}}
+	case "{{.Name}}":
+		if id == "" {
+			{{$m.Receiver}}.{{.Field}} = {{if .Ptr}}nil{{else}}{{.Elem}}{}{{end}}
+			return nil
+		}
+		{{template "related" .}}
+		{{$m.Receiver}}.{{.Field}} = v
+		return nil
+{{- end}}
+	}
+	return errors.New("There is no to-one relationship with the name " + name)
+}
+{{end}}
+{{- if $m.ToMany}}
+{{- if $m.Generate "SetToManyReferenceIDs"}}
+// SetToManyReferenceIDs implements api2go.UnmarshalToManyRelations.
+func ({{$m.Receiver}} *{{$m.Name}}) SetToManyReferenceIDs(name string, ids []string) error {
+	switch name {
+{{- range $m.ToMany}}
+	case "{{.Name}}":
+		related := make([]{{.Elements}}, 0, len(ids))
+		for _, id := range ids {
+			{{template "related" .}}
+			related = append(related, v)
+		}
+		{{$m.Receiver}}.{{.Field}} = related
+		return nil
+{{- end}}
+	}
+	return errors.New("There is no to-many relationship with the name " + name)
+}
+{{end}}
+{{- if $m.Generate "AddToManyIDs"}}
+// AddToManyIDs implements api2go.EditToManyRelations. IDs which are already
+// linked are kept once.
+func ({{$m.Receiver}} *{{$m.Name}}) AddToManyIDs(name string, ids []string) error {
+	switch name {
+{{- range $m.ToMany}}
+	case "{{.Name}}":
+		linked := map[string]bool{}
+		for _, v := range {{$m.Receiver}}.{{.Field}} {
+			linked[v.GetID()] = true
+		}
+		for _, id := range ids {
+			if linked[id] {
+				continue
+			}
+			linked[id] = true
+			{{template "related" .}}
+			{{$m.Receiver}}.{{.Field}} = append({{$m.Receiver}}.{{.Field}}, v)
+		}
+		return nil
+{{- end}}
+	}
+	return errors.New("There is no to-many relationship with the name " + name)
+}
+{{end}}
+{{- if $m.Generate "DeleteToManyIDs"}}
+// DeleteToManyIDs implements api2go.EditToManyRelations.
+func ({{$m.Receiver}} *{{$m.Name}}) DeleteToManyIDs(name string, ids []string) error {
+	switch name {
+{{- range $m.ToMany}}
+	case "{{.Name}}":
+		obsolete := map[string]bool{}
+		for _, id := range ids {
+			obsolete[id] = true
+		}
+		kept := make([]{{.Elements}}, 0, len({{$m.Receiver}}.{{.Field}}))
+		for _, v := range {{$m.Receiver}}.{{.Field}} {
+			if !obsolete[v.GetID()] {
+				kept = append(kept, v)
+			}
+		}
+		{{$m.Receiver}}.{{.Field}} = kept
+		return nil
+{{- end}}
+	}
+	return errors.New("There is no to-many relationship with the name " + name)
+}
+{{end}}
+{{- end}}
+{{- if $m.Generate "MarshalJSONAPINode"}}
+{{template "marshal" $m}}
+{{end}}
+{{- if $m.Generate "UnmarshalJSONAPINode"}}
+{{template "unmarshal" $m}}
+{{end}}
+{{- if $.Store}}
+{{template "resource" (resource $m $.Store)}}
+{{- end}}
+{{- end}}
+`
+
+// relatedSrc declares v as the related object of a relation with id.
+const relatedSrc = `
+{{- if .Ptr}}v := &{{.Elem}}{}{{else}}var v {{.Elem}}{{end}}
+		if err := v.SetID(id); err != nil {
+			return err
+		}`
+
+// resourceSrc is the template of the typed resource skeleton of a model.
+const resourceSrc = `
+// {{.Name}}Resource is the typed data source of {{.Name}}, see
+// api2go.TypedCRUD. It keeps the objects in a {{.Store}}.Store and is the
+// place for the logic of the resource:
+//
+//	api2go.AddTypedResource[*{{.Name}}](api, rg, resource)
+type {{.Name}}Resource struct {
+	Store *{{.Store}}.Store
+}
+{{if eq .Store "memstore"}}
+// New{{.Name}}Resource returns a {{.Name}}Resource with an empty store.
+func New{{.Name}}Resource() *{{.Name}}Resource {
+	return &{{.Name}}Resource{Store: memstore.New(&{{.Name}}{})}
+}
+{{else}}
+// New{{.Name}}Resource returns a {{.Name}}Resource on db.
+func New{{.Name}}Resource(db *sql.DB) (*{{.Name}}Resource, error) {
+	store, err := sqlstore.New(db, &{{.Name}}{})
+	if err != nil {
+		return nil, err
+	}
+	return &{{.Name}}Resource{Store: store}, nil
+}
+{{end}}
+// FindOne implements api2go.TypedCRUD.
+func (r *{{.Name}}Resource) FindOne(ctx context.Context, id string) (*{{.Name}}, error) {
+	req, _ := api2go.RequestFromContext(ctx)
+	rsp, err := r.Store.FindOne(id, req)
+	if err != nil {
+		return nil, err
+	}
+	return rsp.Result().(*{{.Name}}), nil
+}
+
+// FindAll implements api2go.TypedFindAll.
+func (r *{{.Name}}Resource) FindAll(ctx context.Context) ([]*{{.Name}}, error) {
+	req, _ := api2go.RequestFromContext(ctx)
+	rsp, err := r.Store.FindAll(req)
+	if err != nil {
+		return nil, err
+	}
+	return rsp.Result().([]*{{.Name}}), nil
+}
+
+// Create implements api2go.TypedCRUD.
+func (r *{{.Name}}Resource) Create(ctx context.Context, obj *{{.Name}}) (*{{.Name}}, error) {
+	req, _ := api2go.RequestFromContext(ctx)
+	rsp, err := r.Store.Create(obj, req)
+	if err != nil {
+		return nil, err
+	}
+	return rsp.Result().(*{{.Name}}), nil
+}
+
+// Update implements api2go.TypedCRUD.
+func (r *{{.Name}}Resource) Update(ctx context.Context, obj *{{.Name}}) (*{{.Name}}, error) {
+	req, _ := api2go.RequestFromContext(ctx)
+	rsp, err := r.Store.Update(obj, req)
+	if err != nil {
+		return nil, err
+	}
+	if updated, ok := rsp.Result().(*{{.Name}}); ok {
+		return updated, nil
+	}
+	return obj, nil
+}
+
+// Delete implements api2go.TypedCRUD.
+func (r *{{.Name}}Resource) Delete(ctx context.Context, id string) error {
+	req, _ := api2go.RequestFromContext(ctx)
+	_, err := r.Store.Delete(id, req)
+	return err
+}`
+
+// marshalSrc is the template of MarshalJSONAPINode.
+const marshalSrc = `
+// MarshalJSONAPINode implements api2go.MarshalNode.
+func ({{.Receiver}} {{.Name}}) MarshalJSONAPINode(si jsonapi.ServerInformation) *jsonapi.Node {
+	node := &jsonapi.Node{
+		Type:          "{{.Type}}",
+		ID:            {{.Receiver}}.GetID(),
+		Attributes:    map[string]interface{}{},
+		Relationships: map[string]interface{}{},
+	}
+{{- range .Attrs}}
+{{- if .NonEmpty}}
+	if {{.Cond $.Receiver}} {
+		node.Attributes["{{.Name}}"] = {{template "attrValue" (attr $ .)}}
+	}
+{{- else}}
+	node.Attributes["{{.Name}}"] = {{template "attrValue" (attr $ .)}}
+{{- end}}
+{{- end}}
+{{- range .ToOne}}
+{{- if .Ptr}}
+	if {{$.Receiver}}.{{.Field}} != nil {
+		node.Relationships["{{.Name}}"] = &jsonapi.RelationshipOneNode{
+			Data: &jsonapi.Node{Type: "{{.Type}}", ID: {{$.Receiver}}.{{.Field}}.GetID()},
+		}
+	}{{if not .OmitEmpty}} else {
+		node.Relationships["{{.Name}}"] = &jsonapi.RelationshipOneNode{}
+	}{{end}}
+{{- else}}
+	node.Relationships["{{.Name}}"] = &jsonapi.RelationshipOneNode{
+		Data: &jsonapi.Node{Type: "{{.Type}}", ID: {{$.Receiver}}.{{.Field}}.GetID()},
+	}
+{{- end}}
+{{- end}}
+{{- range .ToMany}}
+	{{if .OmitEmpty}}if len({{$.Receiver}}.{{.Field}}) > 0 {{end}}{
+		rel := &jsonapi.RelationshipManyNode{
+			Data: make([]*jsonapi.Node, 0, len({{$.Receiver}}.{{.Field}})),
+		}
+		for _, v := range {{$.Receiver}}.{{.Field}} {
+			rel.Data = append(rel.Data, &jsonapi.Node{Type: "{{.Type}}", ID: v.GetID()})
+		}
+		node.Relationships["{{.Name}}"] = rel
+	}
+{{- end}}
+	return node
+}`
+
+// attrValueSrc is the template of the marshalled value of an attribute.
+const attrValueSrc = `
+{{- if eq .Attr.Time "iso8601" -}}
+{{.Receiver}}.{{.Attr.Field}}.UTC().Format("2006-01-02T15:04:05Z")
+{{- else if eq .Attr.Time "unix" -}}
+{{.Receiver}}.{{.Attr.Field}}.Unix()
+{{- else -}}
+{{.Receiver}}.{{.Attr.Field}}
+{{- end}}`
+
+// unmarshalSrc is the template of UnmarshalJSONAPINode.
+const unmarshalSrc = `
+// UnmarshalJSONAPINode implements api2go.UnmarshalNode.
+func ({{.Receiver}} *{{.Name}}) UnmarshalJSONAPINode(node *jsonapi.Node) error {
+	if node.ID != "" {
+		if err := {{.Receiver}}.SetID(node.ID); err != nil {
+			return err
+		}
+	}
+{{- range .Attrs}}
+	if v, ok := node.Attributes["{{.Name}}"]; ok {
+{{- if eq .Time "unix"}}
+		var sec int64
+		if err := api2go.UnmarshalAttribute(v, &sec); err != nil {
+			return err
+		}
+		{{$.Receiver}}.{{.Field}} = time.Unix(sec, 0)
+{{- else}}
+		if err := api2go.UnmarshalAttribute(v, &{{$.Receiver}}.{{.Field}}); err != nil {
+			return err
+		}
+{{- end}}
+	}
+{{- end}}
+{{- range .ToOne}}
+	if rel, ok := node.Relationships["{{.Name}}"].(*jsonapi.RelationshipOneNode); ok {
+		id := ""
+		if rel.Data != nil {
+			id = rel.Data.ID
+		}
+		if err := {{$.Receiver}}.SetToOneReferenceID("{{.Name}}", id); err != nil {
+			return err
+		}
+	}
+{{- end}}
+{{- range .ToMany}}
+	if rel, ok := node.Relationships["{{.Name}}"].(*jsonapi.RelationshipManyNode); ok {
+		ids := make([]string, len(rel.Data))
+		for i, n := range rel.Data {
+			ids[i] = n.ID
+		}
+		if err := {{$.Receiver}}.SetToManyReferenceIDs("{{.Name}}", ids); err != nil {
+			return err
+		}
+	}
+{{- end}}
+	return nil
+}`
+
+// newTemplate parses the templates of the generated file.
+func newTemplate() *template.Template {
+	t := template.New("file").Funcs(template.FuncMap{
+		"resource": func(m model, store string) interface{} {
+			return struct {
+				model
+				Store string
+			}{m, store}
+		},
+		"attr": func(m model, a attribute) interface{} {
+			return struct {
+				Receiver string
+				Attr     attribute
+			}{m.Receiver, a}
+		},
+	})
+	template.Must(t.Parse(fileSrc))
+	template.Must(t.New("related").Parse(relatedSrc))
+	template.Must(t.New("resource").Parse(resourceSrc))
+	template.Must(t.New("marshal").Parse(marshalSrc))
+	template.Must(t.New("attrValue").Parse(attrValueSrc))
+	template.Must(t.New("unmarshal").Parse(unmarshalSrc))
+	return t
+}
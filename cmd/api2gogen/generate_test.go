@@ -0,0 +1,216 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const modelSrc = `package model
+
+import "time"
+
+type User struct {
+	ID     int64     ` + "`jsonapi:\"primary,users\"`" + `
+	Name   string    ` + "`jsonapi:\"attr,name\"`" + `
+	Born   time.Time ` + "`jsonapi:\"attr,born,iso8601,omitempty\"`" + `
+	Friend *User     ` + "`jsonapi:\"relation,friend,omitempty\"`" + `
+	Sweets []*Sweet  ` + "`jsonapi:\"relation,sweets\"`" + `
+}
+
+type Sweet struct {
+	ID string ` + "`jsonapi:\"primary,sweets\"`" + `
+}
+
+func (s Sweet) GetID() string { return "sweet-" + s.ID }
+`
+
+func generateModel(t *testing.T, c config) string {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "model.go"), []byte(modelSrc),
+		0644); err != nil {
+		t.Fatal(err)
+	}
+	c.dir, c.output = dir, "model_api2go.go"
+	src, err := generate(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(src)
+}
+
+func TestGenerate(t *testing.T) {
+	src := generateModel(t, config{types: []string{"User", "Sweet"}})
+	for _, exp := range []string{
+		"// Code generated by api2gogen. DO NOT EDIT.",
+		"package model",
+		"func (u User) GetID() string",
+		"strconv.FormatInt(int64(u.ID), 10)",
+		"u.ID = int64(n)",
+		"func (u *User) SetToOneReferenceID(name, id string) error",
+		`case "friend":`,
+		"v := &User{}",
+		"func (u *User) SetToManyReferenceIDs(name string, ids []string) error",
+		"func (u *User) AddToManyIDs(name string, ids []string) error",
+		"func (u *User) DeleteToManyIDs(name string, ids []string) error",
+		`case "sweets":`,
+		"func (s *Sweet) SetID(id string) error",
+	} {
+		if !strings.Contains(src, exp) {
+			t.Errorf("Expect %q in the generated code:\n%s", exp, src)
+		}
+	}
+	for _, unexp := range []string{
+		"func (s Sweet) GetID() string",
+		"func (s *Sweet) SetToManyReferenceIDs",
+		"Resource",
+		"JSONAPINode",
+	} {
+		if strings.Contains(src, unexp) {
+			t.Errorf("Expect no %q in the generated code:\n%s", unexp, src)
+		}
+	}
+}
+
+func TestGenerateResource(t *testing.T) {
+	src := generateModel(t, config{types: []string{"Sweet"}, store: storeSQL})
+	for _, exp := range []string{
+		`"github.com/cention-sany/api2go/sqlstore"`,
+		"type SweetResource struct",
+		"func NewSweetResource(db *sql.DB) (*SweetResource, error)",
+		"func (r *SweetResource) FindAll(ctx context.Context) ([]*Sweet, error)",
+	} {
+		if !strings.Contains(src, exp) {
+			t.Errorf("Expect %q in the generated code:\n%s", exp, src)
+		}
+	}
+}
+
+func TestGenerateNode(t *testing.T) {
+	src := generateModel(t, config{types: []string{"User", "Sweet"},
+		node: true})
+	for _, exp := range []string{
+		`"github.com/cention-sany/jsonapi"`,
+		"func (u User) MarshalJSONAPINode(si jsonapi.ServerInformation) *jsonapi.Node",
+		`Type:          "users",`,
+		`node.Attributes["name"] = u.Name`,
+		`if !u.Born.IsZero() {`,
+		`u.Born.UTC().Format("2006-01-02T15:04:05Z")`,
+		`Data: &jsonapi.Node{Type: "users", ID: u.Friend.GetID()},`,
+		`rel.Data = append(rel.Data, &jsonapi.Node{Type: "sweets", ID: v.GetID()})`,
+		"func (u *User) UnmarshalJSONAPINode(node *jsonapi.Node) error",
+		`api2go.UnmarshalAttribute(v, &u.Born)`,
+		`u.SetToOneReferenceID("friend", id)`,
+		`u.SetToManyReferenceIDs("sweets", ids)`,
+		"func (s Sweet) MarshalJSONAPINode",
+	} {
+		if !strings.Contains(src, exp) {
+			t.Errorf("Expect %q in the generated code:\n%s", exp, src)
+		}
+	}
+	// friend is omitempty
+	if strings.Contains(src, `node.Relationships["friend"] = &jsonapi.RelationshipOneNode{}`) {
+		t.Errorf("Expect no empty friend relationship:\n%s", src)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "model.go"), []byte(modelSrc), 0644)
+	for _, types := range [][]string{{"Missing"}, {"User", "Chocolate"}} {
+		if _, err := generate(config{dir: dir, types: types}); err == nil {
+			t.Errorf("Expect an error for %v.", types)
+		}
+	}
+}
+
+// roundTripTest compares the generated node methods of User with the
+// marshalling by tags of the jsonapi library.
+const roundTripTest = `package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cention-sany/jsonapi"
+)
+
+type si struct{}
+
+func (si) GetBaseURL() string { return "http://localhost" }
+func (si) GetPrefix() string  { return "/v1/" }
+
+func TestRoundTrip(t *testing.T) {
+	u := &User{ID: 1, Name: "Ann",
+		Born:   time.Date(1990, 5, 4, 3, 2, 1, 0, time.UTC),
+		Friend: &User{ID: 2},
+		Sweets: []*Sweet{{ID: "a"}, {ID: "b"}}}
+	payload, err := jsonapi.MarshalOneWithSI(u, si{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := u.MarshalJSONAPINode(si{})
+	got, _ := json.Marshal(node)
+	want, _ := json.Marshal(payload.Data)
+	if string(got) != string(want) {
+		t.Errorf("Expect the node of the jsonapi library\n%s\nbut got\n%s",
+			want, got)
+	}
+	back := &User{}
+	if err := back.UnmarshalJSONAPINode(node); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, u) {
+		t.Errorf("Expect %+v back but got %+v", u, back)
+	}
+}
+`
+
+// TestGenerateCompiles writes the generated code into a package of the
+// module, vets it and runs roundTripTest on it.
+func TestGenerateCompiles(t *testing.T) {
+	gobin, err := exec.LookPath("go")
+	if testing.Short() || err != nil {
+		t.Skip("needs the go command")
+	}
+	// in the module, so that the imports resolve
+	dir, err := os.MkdirTemp(".", "roundtrip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	// Sweet without the GetID of modelSrc, which the library does not call
+	src := strings.Replace(modelSrc,
+		`func (s Sweet) GetID() string { return "sweet-" + s.ID }`, "", 1)
+	files := map[string]string{
+		"model.go":          src,
+		"roundtrip_test.go": roundTripTest,
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src),
+			0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gen, err := generate(config{dir: dir, output: "model_api2go.go",
+		types: []string{"User", "Sweet"}, node: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "model_api2go.go"), gen,
+		0644); err != nil {
+		t.Fatal(err)
+	}
+	pkg := "./" + filepath.Base(dir)
+	for _, args := range [][]string{{"vet", pkg}, {"test", "-count=1", pkg}} {
+		out, err := exec.Command(gobin, args...).CombinedOutput()
+		if err != nil {
+			t.Errorf("go %s: %v\n%s\n%s", strings.Join(args, " "), err, out,
+				gen)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Command api2gogen writes the api2go methods of jsonapi tagged models, to be
+// run by go generate:
+//
+//	//go:generate go run github.com/cention-sany/api2go/cmd/api2gogen -type User,Chocolate
+//
+// For every type it writes GetID and SetID for the primary field, which is
+// a string or an integer, and for relation fields SetToOneReferenceID,
+// SetToManyReferenceIDs, AddToManyIDs and DeleteToManyIDs. Related objects
+// are created with the SetID method of their type. Methods which the package
+// declares already are left out, so any of them can be written by hand.
+// References are marshalled from the relation tags and need no methods.
+//
+// With -node it writes MarshalJSONAPINode and UnmarshalJSONAPINode, see
+// api2go.MarshalNode and api2go.UnmarshalNode, which marshal and unmarshal
+// the model without reflection. The types of related objects must be
+// declared in the package and time.Time attributes are unix timestamps or,
+// with the iso8601 tag option, strings.
+//
+// With -store memstore or -store sqlstore it writes a typed resource
+// skeleton, TypeResource, for api2go.AddTypedResource as well, which keeps
+// the objects in a memstore.Store or sqlstore.Store.
+//
+// The output is written to the file of go generate with the suffix
+// _api2go.go, e.g. model_user_api2go.go, unless -output is set.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	var (
+		typeNames = flag.String("type", "", "comma separated list of the "+
+			"struct type names; required")
+		store = flag.String("store", storeNone, "write a typed resource "+
+			"skeleton on \"memstore\" or \"sqlstore\"")
+		node = flag.Bool("node", false, "write MarshalJSONAPINode and "+
+			"UnmarshalJSONAPINode as well")
+		output = flag.String("output", "", "output file name; default "+
+			"<file>_api2go.go of $GOFILE")
+	)
+	flag.Parse()
+	if err := run(*typeNames, *store, *node, *output); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(typeNames, store string, node bool, output string) error {
+	if typeNames == "" {
+		return fmt.Errorf("api2gogen: -type is required")
+	}
+	switch store {
+	case storeNone, storeMem, storeSQL:
+	default:
+		return fmt.Errorf("api2gogen: unknown store %q", store)
+	}
+	if output == "" {
+		output = "api2go_gen.go"
+		if file := os.Getenv("GOFILE"); file != "" {
+			output = strings.TrimSuffix(file, ".go") + "_api2go.go"
+		}
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	src, err := generate(config{
+		dir:    dir,
+		pkg:    os.Getenv("GOPACKAGE"),
+		types:  strings.Split(typeNames, ","),
+		store:  store,
+		node:   node,
+		output: filepath.Base(output),
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(output, src, 0644)
+}
@@ -0,0 +1,90 @@
+package api2go
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// Codec encodes the response documents and decodes the relationship request
+// bodies of an API. Set API.Codec to plug in a faster JSON library than
+// encoding/json. The values passed implement json.Marshaler and
+// json.Unmarshaler where needed, so the library must support them.
+type Codec interface {
+	// Encode writes the JSON encoding of v to w.
+	Encode(w io.Writer, v interface{}) error
+	// Decode reads the JSON value from r and stores it in v.
+	Decode(r io.Reader, v interface{}) error
+}
+
+// StdCodec is the Codec of encoding/json. It is used when API.Codec is nil.
+type StdCodec struct{}
+
+// Encode implements Codec.
+func (StdCodec) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// Decode implements Codec.
+func (StdCodec) Decode(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// codec returns the Codec of the API.
+func (api *API) codec() Codec {
+	if api.Codec != nil {
+		return api.Codec
+	}
+	return StdCodec{}
+}
+
+// maxPooledBuffer is the capacity above which an output buffer is dropped
+// instead of put back into the pool, so that one large response does not
+// pin its memory.
+const maxPooledBuffer = 4 << 20
+
+var buffers = sync.Pool{New: func() interface{} {
+	return new(bytes.Buffer)
+}}
+
+// pooling can be switched off by the benchmarks to compare against a new
+// buffer per response.
+var pooling = true
+
+// getBuffer returns an empty output buffer from the pool.
+func getBuffer() *bytes.Buffer {
+	if !pooling {
+		return new(bytes.Buffer)
+	}
+	return buffers.Get().(*bytes.Buffer)
+}
+
+// putBuffer resets buf and puts it back into the pool.
+func putBuffer(buf *bytes.Buffer) {
+	if !pooling || buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	buffers.Put(buf)
+}
+
+// payload returns the document which the Codec encodes for v, the payload
+// of a *Doc or *RelationNode and else v itself.
+func payload(v interface{}) interface{} {
+	switch d := v.(type) {
+	case *Doc:
+		if d.one != nil {
+			return d.one
+		} else if d.many != nil {
+			return d.many
+		}
+	case *RelationNode:
+		if d.one != nil {
+			return d.one
+		} else if d.many != nil {
+			return d.many
+		}
+	}
+	return v
+}
@@ -0,0 +1,118 @@
+package api2go_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	. "github.com/cention-sany/api2go"
+)
+
+// countingCodec counts the calls of StdCodec.
+type countingCodec struct {
+	StdCodec
+	encoded, decoded int
+}
+
+func (c *countingCodec) Encode(w io.Writer, v interface{}) error {
+	c.encoded++
+	return c.StdCodec.Encode(w, v)
+}
+
+func (c *countingCodec) Decode(r io.Reader, v interface{}) error {
+	c.decoded++
+	if err := c.StdCodec.Decode(r, v); err != nil {
+		return errors.New("codec: invalid document")
+	}
+	return nil
+}
+
+func TestCodec(t *testing.T) {
+	router := NewHTTPRouter("/v1")
+	api := NewAPI("v1", NewStaticResolver(""))
+	codec := &countingCodec{}
+	api.Codec = codec
+	api.AddResourceWithRouter(router, &post{}, newPostSource("a", "b"))
+	api.AddResourceWithRouter(router, &author{}, &relationSource{})
+
+	if rec := serve(router, "GET", "/v1/posts", ""); rec.Code != http.StatusOK ||
+		codec.encoded != 1 {
+		t.Errorf("Expect the response encoded by the codec but got %d %d: %s",
+			codec.encoded, rec.Code, rec.Body)
+	}
+	rec := serve(router, "POST", "/v1/authors/1/relationships/posts",
+		`{"data":[{"type":"posts","id":"1"}]}`)
+	if rec.Code != http.StatusNoContent || codec.decoded != 1 {
+		t.Errorf("Expect the body decoded by the codec but got %d %d: %s",
+			codec.decoded, rec.Code, rec.Body)
+	}
+	rec = serve(router, "POST", "/v1/authors/1/relationships/posts",
+		`{"data":[`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expect status %d for an error of the codec but got %d.",
+			http.StatusBadRequest, rec.Code)
+	}
+
+	// the resource object of create and update is decoded once for all
+	// checks and the unmarshaling
+	codec.decoded = 0
+	rec = serve(router, "POST", "/v1/posts",
+		`{"data":{"type":"posts","attributes":{"title":"c"}}}`)
+	if rec.Code != http.StatusCreated || codec.decoded != 1 {
+		t.Errorf("Expect the body of create decoded once but got %d %d: %s",
+			codec.decoded, rec.Code, rec.Body)
+	}
+	rec = serve(router, "PATCH", "/v1/posts/1",
+		`{"data":{"type":"posts","id":"1","attributes":{"title":"d"}}}`)
+	if rec.Code != http.StatusOK || codec.decoded != 2 {
+		t.Errorf("Expect the body of update decoded once but got %d %d: %s",
+			codec.decoded, rec.Code, rec.Body)
+	}
+}
+
+// marshalCodec encodes with json.Marshal into a new slice for every
+// response and copies it to the output buffer.
+type marshalCodec struct {
+	StdCodec
+}
+
+func (marshalCodec) Encode(w io.Writer, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func BenchmarkCodec(b *testing.B) {
+	for _, codec := range []struct {
+		name    string
+		codec   Codec
+		pooling bool
+	}{
+		{"std", StdCodec{}, true},
+		{"std-unpooled", StdCodec{}, false},
+		{"marshal", marshalCodec{}, true},
+		// json.Marshal into a new buffer, as before the pool and codec
+		{"marshal-unpooled", marshalCodec{}, false},
+	} {
+		for _, n := range []int{10, 1000, 100000} {
+			b.Run(fmt.Sprint(codec.name, "/", n), func(b *testing.B) {
+				SetPooling(codec.pooling)
+				defer SetPooling(true)
+				router := newAccountAPIWithCodec(n, codec.codec)
+				b.SetBytes(int64(serve(router, "GET", "/v1/accounts", "").
+					Body.Len()))
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					serve(router, "GET", "/v1/accounts", "")
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package api2go
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// malformed returns the 400 Bad Request for a request document which is no
+// valid JSON or of the wrong shape.
+func malformed(err error) error {
+	return NewHTTPError(err, "Malformed JSON: "+err.Error(),
+		http.StatusBadRequest)
+}
+
+// invalidResource returns the 400 Bad Request for a resource object which is
+// valid JSON but can not be unmarshalled into the model, e.g. for an
+// attribute of the wrong type.
+func invalidResource(err error) error {
+	return NewHTTPError(err, "Invalid resource object: "+err.Error(),
+		http.StatusBadRequest)
+}
+
+// resourceObject is the resource object of a create or update request
+// document. It is decoded once and shared by the checks and the unmarshaler.
+type resourceObject struct {
+	Type          *string                    `json:"type"`
+	ID            *string                    `json:"id"`
+	ClientID      string                     `json:"client-id"`
+	Attributes    map[string]json.RawMessage `json:"attributes"`
+	Relationships map[string]struct {
+		Data linkage `json:"data"`
+	} `json:"relationships"`
+}
+
+// decodeResourceObject decodes the resource object of the create or update
+// request document body with the Codec of the API.
+func (res *resource) decodeResourceObject(body []byte) (*resourceObject,
+	error) {
+	var doc struct {
+		Data *resourceObject `json:"data"`
+	}
+	if err := res.api.codec().Decode(bytes.NewReader(body), &doc); err != nil {
+		return nil, malformed(err)
+	}
+	if doc.Data == nil {
+		return nil, NewSourceError(http.StatusBadRequest, "/data",
+			"A resource object is required")
+	}
+	return doc.Data, nil
+}
+
+// id returns the id of the resource object, "" if there is none.
+func (ro *resourceObject) id() string {
+	if ro.ID == nil {
+		return ""
+	}
+	return *ro.ID
+}
+
+// checkResourceObject checks the resource object of a create or update
+// request before it reaches the data source. A type other than typ or an id
+// other than id is a 409 Conflict. id is "" on create, where any id is left
+// to the ClientIDPolicy.
+func checkResourceObject(ro *resourceObject, typ, id string) error {
+	httpErr := NewHTTPError(nil, "Conflicting resource object",
+		http.StatusConflict)
+	if ro.Type != nil && *ro.Type != typ {
+		httpErr.AddSourceError("/data/type", fmt.Sprintf(
+			"The type %s does not match the endpoint %s", *ro.Type, typ))
+	}
+	if id != "" && ro.ID != nil && *ro.ID != id {
+		httpErr.AddSourceError("/data/id", fmt.Sprintf(
+			"The id %s does not match the endpoint id %s", *ro.ID, id))
+	}
+	if len(httpErr.E) > 0 {
+		return httpErr
+	}
+	return nil
+}
+
+// resourceIdentifier is a resource identifier object of a resource linkage.
+type resourceIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// linkage is the primary data of a relationship request document, which is
+// null, one resource identifier or an array of them.
+type linkage struct {
+	present bool
+	isMany  bool
+	one     *resourceIdentifier
+	many    []resourceIdentifier
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (l *linkage) UnmarshalJSON(data []byte) error {
+	l.present = true
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		return nil
+	case len(data) > 0 && data[0] == '[':
+		l.isMany = true
+		return json.Unmarshal(data, &l.many)
+	}
+	l.one = &resourceIdentifier{}
+	return json.Unmarshal(data, l.one)
+}
+
+// ids returns the IDs of a to-many linkage.
+func (l linkage) ids() []string {
+	ids := make([]string, len(l.many))
+	for i, ri := range l.many {
+		ids[i] = ri.ID
+	}
+	return ids
+}
+
+// decodeLinkage decodes the request body of a relationship route, which is
+// a resource linkage of relation. Linkage of another type than the
+// relationship is a 409 Conflict.
+func (res *resource) decodeLinkage(c *routeContext,
+	relation relationship) (linkage, error) {
+	var doc struct {
+		Data linkage `json:"data"`
+	}
+	if err := res.decodeBody(c, &doc); err != nil {
+		return doc.Data, err
+	}
+	l := doc.Data
+	if !l.present {
+		return l, NewSourceError(http.StatusBadRequest, "/data",
+			"Invalid object. Need a \"data\" object")
+	}
+	missing := NewHTTPError(nil, "Invalid resource linkage",
+		http.StatusBadRequest)
+	conflict := NewHTTPError(nil, "Conflicting resource linkage",
+		http.StatusConflict)
+	check := func(ri resourceIdentifier, pointer string) {
+		if ri.ID == "" {
+			missing.AddSourceError(pointer+"/id", fmt.Sprintf(
+				"The linkage of %s must have an id", relation.name))
+		}
+		if ri.Type != "" && ri.Type != relation.typ {
+			conflict.AddSourceError(pointer+"/type", fmt.Sprintf(
+				"The type %s does not match the relationship %s of type %s",
+				ri.Type, relation.name, relation.typ))
+		}
+	}
+	if l.isMany {
+		for i, ri := range l.many {
+			check(ri, "/data/"+strconv.Itoa(i))
+		}
+	} else if l.one != nil {
+		check(*l.one, "/data")
+	}
+	if len(missing.E) > 0 {
+		return l, missing
+	}
+	if len(conflict.E) > 0 {
+		return l, conflict
+	}
+	return l, nil
+}
@@ -0,0 +1,71 @@
+package api2go_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/cention-sany/api2go"
+)
+
+func TestConflictChecks(t *testing.T) {
+	router := NewHTTPRouter("/v1")
+	api := NewAPI("v1", NewStaticResolver(""))
+	// the checks must answer before the panicking sources are called
+	api.AddResourceWithRouter(router, &post{}, panickySource{})
+	api.AddResourceWithRouter(router, &author{}, panickySource{})
+
+	tests := []struct {
+		method, target, body string
+		code                 int
+		pointers             []string
+	}{
+		{"POST", "/v1/posts", `{"data":{"type":"authors"}}`,
+			http.StatusConflict, []string{"/data/type"}},
+		{"POST", "/v1/posts", `{"data":{"type":"posts"`,
+			http.StatusBadRequest, nil},
+		{"PATCH", "/v1/posts/1", `{"data":{"type":"authors","id":"2"}}`,
+			http.StatusConflict, []string{"/data/type", "/data/id"}},
+		{"PATCH", "/v1/posts/1", `{"data":{"type":"posts","id":"2"}}`,
+			http.StatusConflict, []string{"/data/id"}},
+		{"PATCH", "/v1/posts/1", `{"data":`, http.StatusBadRequest, nil},
+		{"PATCH", "/v1/posts/1", `{}`, http.StatusBadRequest,
+			[]string{"/data"}},
+		{"PATCH", "/v1/authors/1/relationships/posts",
+			`{"data":[{"type":"posts","id":"1"},{"type":"authors","id":"2"}]}`,
+			http.StatusConflict, []string{"/data/1/type"}},
+		{"PATCH", "/v1/authors/1/relationships/posts", `{"data":[`,
+			http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		rec := serve(router, tt.method, tt.target, tt.body)
+		if rec.Code != tt.code {
+			t.Errorf("Expect status %d for %s %s %s but got %d: %s", tt.code,
+				tt.method, tt.target, tt.body, rec.Code, rec.Body)
+			continue
+		}
+		for _, p := range tt.pointers {
+			if !strings.Contains(rec.Body.String(), `"pointer":"`+p+`"`) {
+				t.Errorf("Expect pointer %s for %s %s but got %s", p,
+					tt.method, tt.target, rec.Body)
+			}
+		}
+	}
+}
+
+func TestWrongAttributeType(t *testing.T) {
+	router := newAccountAPI(1)
+	for _, req := range []struct{ method, target, body string }{
+		{"POST", "/v1/accounts",
+			`{"data":{"type":"accounts","attributes":{"name":5}}}`},
+		{"PATCH", "/v1/accounts/1",
+			`{"data":{"type":"accounts","id":"1","attributes":{"name":5}}}`},
+	} {
+		rec := serve(router, req.method, req.target, req.body)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expect status %d for %s %s but got %d: %s",
+				http.StatusBadRequest, req.method, req.target, rec.Code,
+				rec.Body)
+		}
+	}
+}
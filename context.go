@@ -2,6 +2,7 @@ package api2go
 
 import (
 	"context"
+	"sync"
 )
 
 // APIContexter embedding context.Context and requesting two helper functions
@@ -10,3 +11,65 @@ type APIContexter interface {
 	Set(key string, value interface{})
 	Get(key string) (interface{}, bool)
 }
+
+// ValueStore is a request scoped key value store, such as *gin.Context.
+type ValueStore interface {
+	Set(key string, value interface{})
+	Get(key string) (interface{}, bool)
+}
+
+type valueStoreKey struct{}
+
+// WithValueStore returns a copy of ctx carrying s. Router bindings use it to
+// hand the store of their framework to the APIContexter of a Request, so
+// that values set by middlewares are visible to data sources.
+func WithValueStore(ctx context.Context, s ValueStore) context.Context {
+	return context.WithValue(ctx, valueStoreKey{}, s)
+}
+
+// NewAPIContext returns an APIContexter on top of ctx. Set and Get use the
+// ValueStore carried by ctx, see WithValueStore, or a new one if there is
+// none.
+func NewAPIContext(ctx context.Context) APIContexter {
+	return &apiContext{Context: ctx, store: valueStoreOf(ctx)}
+}
+
+func valueStoreOf(ctx context.Context) ValueStore {
+	if s, ok := ctx.Value(valueStoreKey{}).(ValueStore); ok {
+		return s
+	}
+	return &mapStore{m: map[string]interface{}{}}
+}
+
+type apiContext struct {
+	context.Context
+	store ValueStore
+}
+
+func (c *apiContext) Set(key string, value interface{}) {
+	c.store.Set(key, value)
+}
+
+func (c *apiContext) Get(key string) (interface{}, bool) {
+	return c.store.Get(key)
+}
+
+// mapStore is the ValueStore of requests which are not served by a
+// framework with its own store.
+type mapStore struct {
+	mu sync.RWMutex
+	m  map[string]interface{}
+}
+
+func (s *mapStore) Set(key string, value interface{}) {
+	s.mu.Lock()
+	s.m[key] = value
+	s.mu.Unlock()
+}
+
+func (s *mapStore) Get(key string) (interface{}, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.m[key]
+	return v, ok
+}
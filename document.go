@@ -55,6 +55,16 @@ func (d *Doc) included() []*ja.Node {
 	return nil
 }
 
+// setIncluded replaces the included resources. The shared empty documents
+// are left untouched.
+func (d *Doc) setIncluded(nodes []*ja.Node) {
+	if d.one != nil && d.one != EmptyObject {
+		d.one.Included = nodes
+	} else if d.many != nil && d.many != EmptyArray {
+		d.many.Included = nodes
+	}
+}
+
 func (d *Doc) meta(v ...*ja.Meta) *ja.Meta {
 	var (
 		m     *ja.Meta
@@ -193,6 +203,7 @@ func (r *RelationNode) MarshalJSON() ([]byte, error) {
 	return nil, errors.New("api2go: no relation node to marshal")
 }
 
+// marshalToDoc marshals the result v of a data source into a document.
 func marshalToDoc(v interface{}, info information) (*Doc, error) {
 	if v == nil {
 		return &Doc{one: EmptyObject}, nil
@@ -209,20 +220,20 @@ func marshalToDoc(v interface{}, info information) (*Doc, error) {
 		for i := 0; i < size; i++ {
 			vs = append(vs, value.Index(i).Interface())
 		}
-		many, err := ja.MarshalManyWithSI(vs, info)
+		many, err := marshalMany(vs, info)
 		if err != nil {
 			return nil, err
 		}
 		return &Doc{many: many}, nil
 	case reflect.Struct, reflect.Ptr:
 		if k == reflect.Struct {
-			if reflect.Zero(value.Type()).Interface() == v {
+			if value.IsZero() {
 				return &Doc{one: EmptyObject}, nil
 			}
 		} else if value.IsNil() {
 			return &Doc{one: EmptyObject}, nil
 		}
-		one, err := ja.MarshalOneWithSI(v, info)
+		one, err := marshalOne(v, info)
 		if err != nil {
 			return nil, err
 		}
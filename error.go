@@ -1,6 +1,7 @@
 package api2go
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -10,10 +11,27 @@ import (
 
 // HTTPError is used for errors
 type HTTPError struct {
-	err    error
-	msg    string
-	status int
-	E      []*jsonapi.ErrorObject
+	err     error
+	msg     string
+	status  int
+	E       []*jsonapi.ErrorObject
+	sources map[*jsonapi.ErrorObject]*ErrorSource
+}
+
+// ErrorSource points to the part of the request which caused an error
+// object, see http://jsonapi.org/format/#error-objects.
+type ErrorSource struct {
+	// Pointer is a JSON pointer into the request document, e.g.
+	// "/data/attributes/title".
+	Pointer string `json:"pointer,omitempty"`
+	// Parameter is the query parameter which caused the error.
+	Parameter string `json:"parameter,omitempty"`
+}
+
+// errorObject adds the source member, which jsonapi.ErrorObject lacks.
+type errorObject struct {
+	*jsonapi.ErrorObject
+	Source *ErrorSource `json:"source,omitempty"`
 }
 
 // NewHTTPError creates a new error with message and status code.
@@ -41,6 +59,51 @@ func NewCustomError(err error, httpCode, appCode int) HTTPError {
 	}
 }
 
+// NewSourceError creates an error with status whose only error object points
+// to the member of the request document at pointer.
+func NewSourceError(status int, pointer, detail string) HTTPError {
+	e := HTTPError{msg: detail, status: status}
+	e.AddSourceError(pointer, detail)
+	return e
+}
+
+// AddSourceError appends an error object with the status of e pointing to
+// the member of the request document at pointer.
+func (e *HTTPError) AddSourceError(pointer, detail string) {
+	o := &jsonapi.ErrorObject{
+		Title:  http.StatusText(e.status),
+		Status: strconv.Itoa(e.status),
+		Detail: detail,
+	}
+	e.E = append(e.E, o)
+	e.SetSource(o, ErrorSource{Pointer: pointer})
+}
+
+// AddParameterError appends an error object with the status of e caused by
+// the query parameter.
+func (e *HTTPError) AddParameterError(parameter, detail string) {
+	o := &jsonapi.ErrorObject{
+		Title:  http.StatusText(e.status),
+		Status: strconv.Itoa(e.status),
+		Detail: detail,
+	}
+	e.E = append(e.E, o)
+	e.SetSource(o, ErrorSource{Parameter: parameter})
+}
+
+// SetSource sets the source of the error object o, which is one of E.
+func (e *HTTPError) SetSource(o *jsonapi.ErrorObject, source ErrorSource) {
+	if e.sources == nil {
+		e.sources = map[*jsonapi.ErrorObject]*ErrorSource{}
+	}
+	e.sources[o] = &source
+}
+
+// Status returns the http status code of the error.
+func (e HTTPError) Status() int {
+	return e.status
+}
+
 // Error returns a nice string represenation including the status
 func (e HTTPError) Error() string {
 	msg := fmt.Sprintf("http error (%d) %s and %d more errors", e.status, e.msg,
@@ -66,7 +129,14 @@ func (e HTTPError) Render(w http.ResponseWriter) error {
 		}}
 	}
 	e.WriteContentType(w)
-	return jsonapi.MarshalErrors(w, e.E)
+	if len(e.sources) == 0 {
+		return jsonapi.MarshalErrors(w, e.E)
+	}
+	errs := make([]errorObject, len(e.E))
+	for i, o := range e.E {
+		errs[i] = errorObject{ErrorObject: o, Source: e.sources[o]}
+	}
+	return json.NewEncoder(w).Encode(map[string]interface{}{"errors": errs})
 }
 
 // WriteContentType sets the content type
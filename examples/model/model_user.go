@@ -85,14 +85,18 @@ func (u *User) AddToManyIDs(name string, IDs []string) error {
 // DeleteToManyIDs removes some sweets from a users because they made him very sick
 func (u *User) DeleteToManyIDs(name string, IDs []string) error {
 	if name == "sweets" {
+		obsolete := map[string]bool{}
 		for _, ID := range IDs {
-			for pos, oldID := range u.ChocolatesIDs {
-				if ID == oldID {
-					// match, this ID must be removed
-					u.ChocolatesIDs = append(u.ChocolatesIDs[:pos], u.ChocolatesIDs[pos+1:]...)
-				}
+			obsolete[ID] = true
+		}
+		kept := []string{}
+		for _, oldID := range u.ChocolatesIDs {
+			if !obsolete[oldID] {
+				kept = append(kept, oldID)
 			}
 		}
+		u.ChocolatesIDs = kept
+		return nil
 	}
 
 	return errors.New("There is no to-many relationship with the name " + name)
@@ -0,0 +1,47 @@
+package api2go
+
+import (
+	"reflect"
+
+	"github.com/cention-sany/jsonapi"
+)
+
+// SetPooling switches the pool of the output buffers on or off.
+func SetPooling(on bool) {
+	pooling = on
+}
+
+// SetTypeCache switches the cache of the type metadata on or off.
+func SetTypeCache(on bool) {
+	cacheTypeInfos = on
+}
+
+// ReadTypeInfo reads the metadata of t from its tags on every call, as
+// before the type cache, and CachedTypeInfo takes it from the cache.
+var (
+	ReadTypeInfo = func(t reflect.Type) error {
+		_, err := newTypeInfo(t)
+		return err
+	}
+	CachedTypeInfo = func(t reflect.Type) error {
+		_, err := typeInfoOf(t)
+		return err
+	}
+)
+
+// MarshalTagged marshals v as marshalToDoc does, by its tags unless it
+// implements MarshalNode.
+func MarshalTagged(v interface{}, si jsonapi.ServerInformation) (
+	*jsonapi.OnePayload, error) {
+	return marshalOne(v, si)
+}
+
+// UnmarshalTagged sets obj from the request document body as the create and
+// update handlers do.
+func UnmarshalTagged(body []byte, obj interface{}) error {
+	ro, err := (&resource{api: &API{}}).decodeResourceObject(body)
+	if err != nil {
+		return err
+	}
+	return unmarshal(body, ro, obj)
+}
@@ -0,0 +1,78 @@
+package api2go_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	. "github.com/cention-sany/api2go"
+	"github.com/gin-gonic/gin"
+)
+
+type author struct {
+	ID    string  `jsonapi:"primary,authors"`
+	Name  string  `jsonapi:"attr,name"`
+	Email string  `jsonapi:"attr,email"`
+	Posts []*post `jsonapi:"relation,posts"`
+}
+
+func (a author) GetID() string { return a.ID }
+
+type authorSource struct {
+	panickySource
+	fields map[string][]string
+}
+
+func (s *authorSource) FindOne(id string, req Request) (Responder, error) {
+	s.fields = req.Fields
+	return &Response{Res: &author{ID: id, Name: "Lem", Email: "lem@example.com",
+		Posts: []*post{{ID: "1"}}}, Code: http.StatusOK}, nil
+}
+
+func TestSparseFieldsets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := NewAPI("v1", NewStaticResolver(""))
+	source := &authorSource{}
+	api.AddResource(r.Group("/v1"), &author{}, source)
+
+	get := func(target string) (int, map[string]interface{}) {
+		rec := serve(r, "GET", target, "")
+		var doc struct {
+			Data map[string]interface{}
+		}
+		json.Unmarshal(rec.Body.Bytes(), &doc)
+		return rec.Code, doc.Data
+	}
+
+	code, data := get("/v1/authors/1?fields[authors]=name")
+	if code != http.StatusOK {
+		t.Fatalf("Expect status %d but got %d.", http.StatusOK, code)
+	}
+	if exp := map[string][]string{"authors": {"name"}}; !reflect.DeepEqual(exp,
+		source.fields) {
+		t.Errorf("Expect Request.Fields %v but got %v.", exp, source.fields)
+	}
+	if exp := map[string]interface{}{"name": "Lem"}; !reflect.DeepEqual(exp,
+		data["attributes"]) {
+		t.Errorf("Expect attributes %v but got %v.", exp, data["attributes"])
+	}
+	if _, ok := data["relationships"]; ok {
+		t.Errorf("Expect no relationships but got %v.", data["relationships"])
+	}
+
+	_, data = get("/v1/authors/1?fields[authors]=posts")
+	if _, ok := data["attributes"]; ok {
+		t.Errorf("Expect no attributes but got %v.", data["attributes"])
+	}
+	rels, _ := data["relationships"].(map[string]interface{})
+	if _, ok := rels["posts"]; !ok {
+		t.Errorf("Expect relationship posts but got %v.", data["relationships"])
+	}
+
+	if code, _ = get("/v1/authors/1?fields[authors]=age"); code != http.StatusBadRequest {
+		t.Errorf("Expect status %d for an unknown field but got %d.",
+			http.StatusBadRequest, code)
+	}
+}
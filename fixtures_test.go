@@ -0,0 +1,69 @@
+package api2go_test
+
+import (
+	"net/http"
+	"strconv"
+
+	. "github.com/cention-sany/api2go"
+)
+
+type post struct {
+	ID    string `jsonapi:"primary,posts"`
+	Title string `jsonapi:"attr,title"`
+}
+
+func (p post) GetID() string { return p.ID }
+
+func (p *post) SetID(id string) error {
+	p.ID = id
+	return nil
+}
+
+type postSource struct {
+	posts map[string]*post
+}
+
+func newPostSource(titles ...string) *postSource {
+	s := &postSource{posts: map[string]*post{}}
+	for i, t := range titles {
+		id := strconv.Itoa(i + 1)
+		s.posts[id] = &post{ID: id, Title: t}
+	}
+	return s
+}
+
+func (s *postSource) FindAll(req Request) (Responder, error) {
+	all := []*post{}
+	for i := 1; i <= len(s.posts); i++ {
+		if p, ok := s.posts[strconv.Itoa(i)]; ok {
+			all = append(all, p)
+		}
+	}
+	return &Response{Res: all, Code: http.StatusOK}, nil
+}
+
+func (s *postSource) FindOne(id string, req Request) (Responder, error) {
+	p, ok := s.posts[id]
+	if !ok {
+		return nil, NewHTTPError(nil, "post not found", http.StatusNotFound)
+	}
+	return &Response{Res: p, Code: http.StatusOK}, nil
+}
+
+func (s *postSource) Create(obj interface{}, req Request) (Responder, error) {
+	p := obj.(*post)
+	p.ID = strconv.Itoa(len(s.posts) + 1)
+	s.posts[p.ID] = p
+	return &Response{Res: p, Code: http.StatusCreated}, nil
+}
+
+func (s *postSource) Delete(id string, req Request) (Responder, error) {
+	delete(s.posts, id)
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+func (s *postSource) Update(obj interface{}, req Request) (Responder, error) {
+	p := obj.(*post)
+	s.posts[p.ID] = p
+	return &Response{Res: p, Code: http.StatusOK}, nil
+}
@@ -0,0 +1,88 @@
+package api2go
+
+import (
+	"encoding/hex"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// TotalCountHeader is the response header with the total number of objects
+// of a collection, as returned by PaginatedFindAll or Counter.
+const TotalCountHeader = "X-Total-Count"
+
+// headWriter discards the body of a HEAD response. The bytes are counted as
+// written, so the handlers and the instrumentation behave as for GET.
+type headWriter struct {
+	http.ResponseWriter
+}
+
+func (headWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// head returns the HEAD handler of the GET handler get, which answers with
+// the status and headers of get but without the body.
+func head(get handler) handler {
+	return func(c *routeContext) {
+		c.Writer.ResponseWriter = headWriter{c.Writer.ResponseWriter}
+		get(c)
+	}
+}
+
+// isHead tells if the request is a HEAD request.
+func isHead(c *routeContext) bool {
+	return c.Request.Method == http.MethodHead
+}
+
+// handleCount answers HEAD on the collection with the Counter of the data
+// source instead of reading the objects.
+func (res *resource) handleCount(c *routeContext, source Counter) error {
+	req, span := sourceRequest(c, SpanCount)
+	count, err := source.Count(req)
+	endSpan(span, err)
+	if err != nil {
+		return err
+	}
+	c.Header("Content-Type", res.api.ContentType)
+	c.Header(TotalCountHeader, strconv.FormatUint(uint64(count), 10))
+	c.Writer.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// handleExists answers HEAD on a resource with the Exister of the data
+// source instead of reading the object.
+func (res *resource) handleExists(c *routeContext, source Exister) error {
+	req, span := sourceRequest(c, SpanExists)
+	ok, err := source.Exists(c.Param(idStr), req)
+	endSpan(span, err)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return NewOnlyHTTPError(http.StatusNotFound)
+	}
+	c.Header("Content-Type", res.api.ContentType)
+	c.Writer.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// etagOf returns the strong entity tag of a response body.
+func etagOf(body []byte) string {
+	h := fnv.New64a()
+	h.Write(body)
+	return `"` + hex.EncodeToString(h.Sum(nil)) + `"`
+}
+
+// etagMatch tells if the If-None-Match header value matches etag, with the
+// weak comparison of RFC 9110.
+func etagMatch(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package api2go_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	. "github.com/cention-sany/api2go"
+)
+
+type countingSource struct {
+	accountListSource
+	finds int
+}
+
+func (s *countingSource) FindAll(req Request) (Responder, error) {
+	s.finds++
+	return s.accountListSource.FindAll(req)
+}
+
+func (s *countingSource) Count(req Request) (uint, error) {
+	return uint(len(s.list)), nil
+}
+
+func (s *countingSource) Exists(id string, req Request) (bool, error) {
+	_, ok := s.accounts[id]
+	return ok, nil
+}
+
+// checkingSource checks IDs for the referential integrity, which does not
+// change HEAD.
+type checkingSource struct {
+	accountListSource
+}
+
+func (s *checkingSource) MissingIDs(ids []string,
+	req Request) ([]string, error) {
+	return nil, nil
+}
+
+func TestHead(t *testing.T) {
+	router := newAccountAPI(2)
+
+	for _, target := range []string{"/v1/accounts", "/v1/accounts/1"} {
+		get := serve(router, "GET", target, "")
+		rec := serve(router, "HEAD", target, "")
+		if rec.Code != get.Code || rec.Body.Len() != 0 {
+			t.Errorf("Expect status %d without body for %s but got %d: %s",
+				get.Code, target, rec.Code, rec.Body)
+		}
+		for _, h := range []string{"Content-Type", "Content-Length", "ETag"} {
+			if v := rec.Header().Get(h); v == "" || v != get.Header().Get(h) {
+				t.Errorf("Expect the %s header %q of GET %s but got %q.", h,
+					get.Header().Get(h), target, v)
+			}
+		}
+		if n := get.Header().Get("Content-Length"); n !=
+			strconv.Itoa(get.Body.Len()) {
+			t.Errorf("Expect Content-Length %d but got %s.", get.Body.Len(), n)
+		}
+
+		req, _ := http.NewRequest("GET", target, nil)
+		req.Header.Set("If-None-Match", `"other", `+get.Header().Get("ETag"))
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("Expect status %d for %s but got %d: %s",
+				http.StatusNotModified, target, rec.Code, rec.Body)
+		}
+	}
+
+	rec := serve(router, "HEAD", "/v1/accounts/9", "")
+	if rec.Code != http.StatusNotFound || rec.Body.Len() != 0 {
+		t.Errorf("Expect status %d without body but got %d: %s",
+			http.StatusNotFound, rec.Code, rec.Body)
+	}
+}
+
+func TestHeadExistenceChecker(t *testing.T) {
+	router := NewHTTPRouter("/v1")
+	api := NewAPI("v1", NewStaticResolver(""))
+	source := &checkingSource{}
+	source.accounts = map[string]*account{"1": {ID: "1"}}
+	api.AddResourceWithRouter(router, &account{}, source)
+
+	rec := serve(router, "HEAD", "/v1/accounts/1", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == "" ||
+		rec.Header().Get("Content-Length") == "" {
+		t.Errorf("Expect the headers of GET but got %d %v", rec.Code,
+			rec.Header())
+	}
+}
+
+func TestHeadSource(t *testing.T) {
+	router := NewHTTPRouter("/v1")
+	api := NewAPI("v1", NewStaticResolver(""))
+	source := &countingSource{}
+	source.accounts = map[string]*account{"1": {ID: "1"}}
+	source.list = []*account{{ID: "1"}, {ID: "2"}, {ID: "3"}}
+	api.AddResourceWithRouter(router, &account{}, source)
+
+	rec := serve(router, "HEAD", "/v1/accounts", "")
+	if rec.Code != http.StatusOK || rec.Header().Get(TotalCountHeader) != "3" ||
+		source.finds != 0 {
+		t.Errorf("Expect the count of Count but got %d %q after %d FindAll.",
+			rec.Code, rec.Header().Get(TotalCountHeader), source.finds)
+	}
+	if rec := serve(router, "HEAD", "/v1/accounts/1", ""); rec.Code !=
+		http.StatusOK {
+		t.Errorf("Expect status %d but got %d.", http.StatusOK, rec.Code)
+	}
+	if rec := serve(router, "HEAD", "/v1/accounts/2", ""); rec.Code !=
+		http.StatusNotFound || rec.Body.Len() != 0 {
+		t.Errorf("Expect status %d without body but got %d: %s",
+			http.StatusNotFound, rec.Code, rec.Body)
+	}
+}
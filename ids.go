@@ -0,0 +1,140 @@
+package api2go
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// ClientIDMode tells whether clients may send the ID of a resource they
+// create, see http://jsonapi.org/format/#crud-creating-client-ids.
+type ClientIDMode int
+
+const (
+	// ClientIDOptional accepts a create request with or without an ID.
+	ClientIDOptional ClientIDMode = iota
+	// ClientIDForbidden answers a create request with an ID with 403
+	// Forbidden.
+	ClientIDForbidden
+	// ClientIDRequired answers a create request without an ID with 403
+	// Forbidden.
+	ClientIDRequired
+)
+
+// IDPolicy is the client generated ID policy of a resource.
+type IDPolicy struct {
+	Mode ClientIDMode
+	// Validate is optional and checks the format of a client generated ID,
+	// e.g. ValidateUUID. An error is answered with 400 Bad Request.
+	Validate func(id string) error
+}
+
+// The ClientIDPolicy interface can be optionally implemented by a data source
+// to restrict the IDs clients send on Create. Without it any client generated
+// ID is passed to Create. A taken ID should be answered by Create with an
+// HTTPError of status 409 Conflict.
+type ClientIDPolicy interface {
+	ClientIDs() IDPolicy
+}
+
+// The IDGenerator interface can be optionally implemented by a data source to
+// set the ID of objects created without a client generated ID, before Create
+// is called. The resource must implement UnmarshalIdentifier.
+type IDGenerator interface {
+	GenerateID(req Request) (string, error)
+}
+
+// ValidateUUID accepts IDs in the canonical UUID form
+// xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx.
+func ValidateUUID(id string) error {
+	if len(id) != 36 {
+		return errors.New("id is no UUID")
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return errors.New("id is no UUID")
+			}
+			continue
+		}
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' ||
+			'A' <= c && c <= 'F') {
+			return errors.New("id is no UUID")
+		}
+	}
+	return nil
+}
+
+// ValidateInteger accepts IDs which are decimal integers.
+func ValidateInteger(id string) error {
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return errors.New("id is no integer")
+	}
+	return nil
+}
+
+// idPolicyOf returns the policy of source, which may be an adapted typed
+// source.
+func idPolicyOf(source interface{}) IDPolicy {
+	if p, ok := sourceAs[ClientIDPolicy](source); ok {
+		return p.ClientIDs()
+	}
+	return IDPolicy{}
+}
+
+// wrappedSource is implemented by data sources adapting another one, so that
+// the optional interfaces of the adapted source are found.
+type wrappedSource interface {
+	wrapped() interface{}
+}
+
+// sourceAs returns source, or the source it adapts, as the optional
+// interface I.
+func sourceAs[I any](source interface{}) (I, bool) {
+	if i, ok := source.(I); ok {
+		return i, true
+	}
+	if w, ok := source.(wrappedSource); ok {
+		return sourceAs[I](w.wrapped())
+	}
+	var zero I
+	return zero, false
+}
+
+// check applies the policy to the client generated id of a create request.
+func (p IDPolicy) check(id string) error {
+	switch {
+	case id == "" && p.Mode == ClientIDRequired:
+		return NewSourceError(http.StatusForbidden, "/data",
+			"A client generated ID is required")
+	case id == "":
+		return nil
+	case p.Mode == ClientIDForbidden:
+		return NewSourceError(http.StatusForbidden, "/data/id",
+			"Client generated IDs are not supported")
+	}
+	if p.Validate != nil {
+		if err := p.Validate(id); err != nil {
+			return NewSourceError(http.StatusBadRequest, "/data/id",
+				err.Error())
+		}
+	}
+	return nil
+}
+
+// generateID sets a generated ID on the new object obj.
+func generateID(g IDGenerator, obj interface{}, req Request) error {
+	id, err := g.GenerateID(req)
+	if err != nil {
+		return err
+	}
+	setter, ok := obj.(UnmarshalIdentifier)
+	if !ok {
+		return fmt.Errorf("api2go: %T must implement SetID to generate IDs",
+			obj)
+	}
+	return setter.SetID(id)
+}
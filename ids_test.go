@@ -0,0 +1,98 @@
+package api2go_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/cention-sany/api2go"
+)
+
+type idSource struct {
+	*postSource
+	policy    IDPolicy
+	generated int
+}
+
+func (s *idSource) ClientIDs() IDPolicy { return s.policy }
+
+func (s *idSource) GenerateID(req Request) (string, error) {
+	s.generated++
+	return fmt.Sprintf("00000000-0000-4000-8000-%012d", s.generated), nil
+}
+
+func (s *idSource) Create(obj interface{}, req Request) (Responder, error) {
+	p := obj.(*post)
+	if _, ok := s.posts[p.ID]; ok {
+		return nil, NewHTTPError(nil, "post exists", http.StatusConflict)
+	}
+	s.posts[p.ID] = p
+	return &Response{Res: p, Code: http.StatusCreated}, nil
+}
+
+func TestClientIDPolicy(t *testing.T) {
+	const uuid = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	body := func(id string) string {
+		if id == "" {
+			return `{"data":{"type":"posts","attributes":{"title":"t"}}}`
+		}
+		return `{"data":{"type":"posts","id":"` + id +
+			`","attributes":{"title":"t"}}}`
+	}
+	tests := []struct {
+		mode    ClientIDMode
+		id      string
+		code    int
+		pointer string
+	}{
+		{ClientIDOptional, "", http.StatusCreated, ""},
+		{ClientIDOptional, uuid, http.StatusCreated, ""},
+		{ClientIDOptional, "1", http.StatusBadRequest, "/data/id"},
+		{ClientIDForbidden, uuid, http.StatusForbidden, "/data/id"},
+		{ClientIDForbidden, "", http.StatusCreated, ""},
+		{ClientIDRequired, "", http.StatusForbidden, "/data"},
+		{ClientIDRequired, uuid, http.StatusCreated, ""},
+	}
+	for _, tt := range tests {
+		router := NewHTTPRouter("/v1")
+		api := NewAPI("v1", NewStaticResolver(""))
+		source := &idSource{postSource: newPostSource(),
+			policy: IDPolicy{Mode: tt.mode, Validate: ValidateUUID}}
+		api.AddResourceWithRouter(router, &post{}, source)
+
+		rec := serve(router, "POST", "/v1/posts", body(tt.id))
+		if rec.Code != tt.code {
+			t.Errorf("Expect status %d for mode %d and id %q but got %d: %s",
+				tt.code, tt.mode, tt.id, rec.Code, rec.Body)
+			continue
+		}
+		if tt.pointer != "" && !strings.Contains(rec.Body.String(),
+			`"pointer":"`+tt.pointer+`"`) {
+			t.Errorf("Expect pointer %s but got %s", tt.pointer, rec.Body)
+		}
+		if tt.code != http.StatusCreated {
+			continue
+		}
+		want := tt.id
+		if want == "" {
+			want = "00000000-0000-4000-8000-000000000001"
+		}
+		if _, ok := source.posts[want]; !ok || len(source.posts) != 1 {
+			t.Errorf("Expect post %s to be created but got %v", want,
+				source.posts)
+		}
+		if tt.id != "" {
+			if rec = serve(router, "POST", "/v1/posts", body(tt.id)); rec.Code != http.StatusConflict {
+				t.Errorf("Expect status %d for a taken id but got %d.",
+					http.StatusConflict, rec.Code)
+			}
+		}
+	}
+}
+
+func TestValidateInteger(t *testing.T) {
+	if ValidateInteger("42") != nil || ValidateInteger("4a") == nil {
+		t.Error("Expect only decimal integers to be valid.")
+	}
+}
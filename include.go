@@ -0,0 +1,257 @@
+package api2go
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	ja "github.com/cention-sany/jsonapi"
+)
+
+// queryParamInclude is the query parameter naming the relationship paths of
+// a compound document, e.g. include=author,comments.author.
+const queryParamInclude = "include"
+
+// includePaths returns the relationship paths of the include parameter.
+func includePaths(c *routeContext) [][]string {
+	var paths [][]string
+	for _, p := range strings.Split(c.Query(queryParamInclude), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, strings.Split(p, "."))
+		}
+	}
+	return paths
+}
+
+// relation returns the relationship name of res, nil if there is none.
+func (res *resource) relation(name string) *relationship {
+	for _, r := range res.info.relations {
+		if r.name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// include adds the resources of the include parameter to doc. Their IDs are
+// collected per path segment and type, so every type is loaded with one
+// FindMany call, and resources loaded once are reused within the request.
+// Relationships to types which are not added to the API keep the included
+// nodes marshalled from the model, resources which are not found are left
+// out.
+func (res *resource) include(c *routeContext, info information,
+	doc *Doc) error {
+	paths := includePaths(c)
+	if len(paths) == 0 {
+		return nil
+	}
+	primary := doc.nodes()
+	if one := doc.node(); one != nil {
+		primary = []*ja.Node{one}
+	}
+	if len(primary) == 0 {
+		return nil
+	}
+	l := c.includeLoader(res.api, info)
+	included := newNodeSet(doc.included())
+	for _, path := range paths {
+		nodes, current := primary, res
+		for _, name := range path {
+			if current == nil {
+				break
+			}
+			relation := current.relation(name)
+			if relation == nil {
+				httpErr := NewHTTPError(nil, "Invalid include parameter",
+					http.StatusBadRequest)
+				httpErr.AddParameterError(queryParamInclude, fmt.Sprintf(
+					"%s has no relationship %s", current.name, name))
+				return httpErr
+			}
+			loaded, err := l.load(c, relation.typ, linkedIDs(nodes, name))
+			if err != nil {
+				return err
+			}
+			included.add(loaded...)
+			nodes, current = loaded, res.api.resource(relation.typ)
+		}
+	}
+	doc.setIncluded(l.drop(included.list))
+	return nil
+}
+
+// linkedIDs returns the IDs linked by relationship name of nodes.
+func linkedIDs(nodes []*ja.Node, name string) []string {
+	var ids []string
+	for _, n := range nodes {
+		switch rel := n.Relationships[name].(type) {
+		case *ja.RelationshipOneNode:
+			if rel.Data != nil {
+				ids = append(ids, rel.Data.ID)
+			}
+		case *ja.RelationshipManyNode:
+			for _, d := range rel.Data {
+				ids = append(ids, d.ID)
+			}
+		}
+	}
+	return ids
+}
+
+// nodeSet is a list of nodes unique by type and ID.
+type nodeSet struct {
+	list  []*ja.Node
+	index map[string]int
+}
+
+func newNodeSet(nodes []*ja.Node) *nodeSet {
+	s := &nodeSet{index: map[string]int{}}
+	s.add(nodes...)
+	return s
+}
+
+// add appends nodes, a node already in the set is replaced.
+func (s *nodeSet) add(nodes ...*ja.Node) {
+	for _, n := range nodes {
+		key := n.Type + "/" + n.ID
+		if i, ok := s.index[key]; ok {
+			s.list[i] = n
+			continue
+		}
+		s.index[key] = len(s.list)
+		s.list = append(s.list, n)
+	}
+}
+
+// includeLoader loads the included resources of one request. It batches the
+// IDs of a type into one FindMany call and caches the marshalled nodes, so
+// no resource is loaded twice.
+type includeLoader struct {
+	api   *API
+	info  information
+	nodes map[string]map[string]*ja.Node // nil for missing resources
+}
+
+// includeLoader returns the loader of the request.
+func (c *routeContext) includeLoader(api *API,
+	info information) *includeLoader {
+	if c.loader == nil {
+		c.loader = &includeLoader{api: api, info: info,
+			nodes: map[string]map[string]*ja.Node{}}
+	}
+	return c.loader
+}
+
+// load returns the nodes of the existing resources of typ with ids.
+func (l *includeLoader) load(c *routeContext, typ string,
+	ids []string) ([]*ja.Node, error) {
+	res := l.api.resource(typ)
+	if res == nil || len(ids) == 0 {
+		return nil, nil
+	}
+	cache, ok := l.nodes[typ]
+	if !ok {
+		cache = map[string]*ja.Node{}
+		l.nodes[typ] = cache
+	}
+	var missing []string
+	for _, id := range ids {
+		if _, ok := cache[id]; !ok {
+			cache[id] = nil
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		objs, err := res.findMany(c, missing)
+		if err != nil {
+			return nil, err
+		}
+		doc, err := marshalToDoc(objs, l.info)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range doc.nodes() {
+			cache[n.ID] = n
+		}
+	}
+	var nodes []*ja.Node
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if n := cache[id]; n != nil && !seen[id] {
+			seen[id] = true
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes, nil
+}
+
+// drop leaves the nodes of resources out which the loader did not find.
+func (l *includeLoader) drop(nodes []*ja.Node) []*ja.Node {
+	kept := nodes[:0]
+	for _, n := range nodes {
+		if found, ok := l.nodes[n.Type][n.ID]; ok && found == nil {
+			continue
+		}
+		kept = append(kept, n)
+	}
+	return kept
+}
+
+// findMany loads the objects with ids, with FindMany if the data source
+// implements it and else with FindOne for every ID. Missing objects are left
+// out.
+func (res *resource) findMany(c *routeContext, ids []string) (interface{},
+	error) {
+	if source, ok := sourceAs[FindMany](res.source); ok {
+		req, span := sourceRequest(c, SpanFindMany)
+		response, err := source.FindMany(ids, req)
+		endSpan(span, err)
+		if err != nil {
+			return nil, err
+		}
+		return response.Result(), nil
+	}
+	var objs []interface{}
+	for _, id := range ids {
+		req, span := sourceRequest(c, SpanFindOne)
+		response, err := res.source.FindOne(id, req)
+		endSpan(span, err)
+		if e, ok := err.(HTTPError); ok && e.Status() == http.StatusNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if obj := response.Result(); obj != nil &&
+			!reflect.ValueOf(obj).IsZero() {
+			objs = append(objs, obj)
+		}
+	}
+	return objs, nil
+}
+
+// filterIDs returns the IDs of filter[id] if it is the only parameter which
+// changes the collection, so that handleIndex can answer it with FindMany.
+func filterIDs(c *routeContext) ([]string, bool) {
+	query := c.Request.URL.Query()
+	values, ok := query["filter[id]"]
+	if !ok {
+		return nil, false
+	}
+	for key := range query {
+		if key != "filter[id]" && (key == "sort" ||
+			strings.HasPrefix(key, "filter[") ||
+			strings.HasPrefix(key, "page[")) {
+			return nil, false
+		}
+	}
+	var ids []string
+	for _, v := range values {
+		for _, id := range strings.Split(v, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, true
+}
@@ -0,0 +1,114 @@
+package api2go_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	. "github.com/cention-sany/api2go"
+)
+
+type batchPostSource struct {
+	*postSource
+	batches [][]string
+}
+
+func (s *batchPostSource) FindMany(ids []string, req Request) (Responder,
+	error) {
+	s.batches = append(s.batches, ids)
+	found := []*post{}
+	for _, id := range ids {
+		if p, ok := s.posts[id]; ok {
+			found = append(found, p)
+		}
+	}
+	return &Response{Res: found, Code: http.StatusOK}, nil
+}
+
+type authorListSource struct {
+	panickySource
+}
+
+func (authorListSource) FindAll(req Request) (Responder, error) {
+	return &Response{Res: []*author{
+		{ID: "1", Posts: []*post{{ID: "1"}, {ID: "2"}}},
+		{ID: "2", Posts: []*post{{ID: "2"}, {ID: "3"}, {ID: "9"}}},
+	}, Code: http.StatusOK}, nil
+}
+
+func TestInclude(t *testing.T) {
+	router := NewHTTPRouter("/v1")
+	api := NewAPI("v1", NewStaticResolver(""))
+	posts := &batchPostSource{postSource: newPostSource("a", "b", "c")}
+	api.AddResourceWithRouter(router, &post{}, posts)
+	api.AddResourceWithRouter(router, &author{}, authorListSource{})
+
+	rec := serve(router, "GET", "/v1/authors?include=posts", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expect status %d but got %d: %s", http.StatusOK, rec.Code,
+			rec.Body)
+	}
+	var doc struct {
+		Included []struct {
+			Type, ID   string
+			Attributes map[string]interface{}
+		}
+	}
+	json.Unmarshal(rec.Body.Bytes(), &doc)
+	titles := map[string]interface{}{}
+	for _, n := range doc.Included {
+		titles[n.ID] = n.Attributes["title"]
+	}
+	if exp := map[string]interface{}{"1": "a", "2": "b", "3": "c"}; !reflect.DeepEqual(exp, titles) {
+		t.Errorf("Expect included posts %v but got %v.", exp, titles)
+	}
+	if exp := [][]string{{"1", "2", "3", "9"}}; !reflect.DeepEqual(exp,
+		posts.batches) {
+		t.Errorf("Expect one batch %v but got %v.", exp, posts.batches)
+	}
+
+	for _, target := range []string{"/v1/authors?include=comments",
+		"/v1/authors?include=posts.author"} {
+		rec = serve(router, "GET", target, "")
+		var errs struct {
+			Errors []struct{ Source ErrorSource }
+		}
+		json.Unmarshal(rec.Body.Bytes(), &errs)
+		if rec.Code != http.StatusBadRequest || len(errs.Errors) != 1 ||
+			errs.Errors[0].Source.Parameter != "include" {
+			t.Errorf("Expect status %d for the include parameter of %s but "+
+				"got %d: %s", http.StatusBadRequest, target, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestFilterIDs(t *testing.T) {
+	router := NewHTTPRouter("/v1")
+	api := NewAPI("v1", NewStaticResolver(""))
+	posts := &batchPostSource{postSource: newPostSource("a", "b", "c")}
+	api.AddResourceWithRouter(router, &post{}, posts)
+
+	ids := func(target string) []string {
+		rec := serve(router, "GET", target, "")
+		var doc struct {
+			Data []struct{ ID string }
+		}
+		json.Unmarshal(rec.Body.Bytes(), &doc)
+		ids := []string{}
+		for _, d := range doc.Data {
+			ids = append(ids, d.ID)
+		}
+		return ids
+	}
+	if got := ids("/v1/posts?filter[id]=3,1"); !reflect.DeepEqual(
+		[]string{"3", "1"}, got) || len(posts.batches) != 1 {
+		t.Errorf("Expect posts 3 and 1 from FindMany but got %v %v.", got,
+			posts.batches)
+	}
+	if got := ids("/v1/posts?filter[id]=3,1&sort=title"); len(got) != 3 ||
+		len(posts.batches) != 1 {
+		t.Errorf("Expect FindAll for sorted requests but got %v %v.", got,
+			posts.batches)
+	}
+}
@@ -0,0 +1,67 @@
+package api2go
+
+import "time"
+
+// Actions are the labels given to Instrumentation for the routes generated by
+// AddResource.
+const (
+	ActionOptions             = "options"
+	ActionIndex               = "index"
+	ActionRead                = "read"
+	ActionReadRelationship    = "relationship-read"
+	ActionReadLinked          = "linked-read"
+	ActionReplaceRelationship = "relationship-replace"
+	ActionAddRelationship     = "relationship-add"
+	ActionDeleteRelationship  = "relationship-delete"
+	ActionCreate              = "create"
+	ActionUpdate              = "update"
+	ActionDelete              = "delete"
+	// HEAD on the GET routes of index, read, relationship-read and
+	// linked-read
+	ActionHeadIndex            = "head-index"
+	ActionHeadRead             = "head-read"
+	ActionHeadReadRelationship = "head-relationship-read"
+	ActionHeadReadLinked       = "head-linked-read"
+)
+
+// Observation is one handled request as seen by Instrumentation.
+type Observation struct {
+	// Resource is the name of the resource, e.g. "users".
+	Resource string
+	// Action is one of the Action constants.
+	Action string
+	// Status is the written http status code.
+	Status int
+	// Duration is the time spent in api2go and the data source.
+	Duration time.Duration
+	// Size is the number of written body bytes.
+	Size int
+}
+
+// Instrumentation can be set on API to observe every request handled by the
+// routes of AddResource. Observe is called after the response was written and
+// must be safe for concurrent use.
+type Instrumentation interface {
+	Observe(Observation)
+}
+
+// instrument is the outermost wrapper of every route. It is a no-op as long
+// as API.Instrumentation is nil.
+func (api *API) instrument(resource, action string, next handler) handler {
+	return func(c *routeContext) {
+		if api.Instrumentation == nil {
+			next(c)
+			return
+		}
+		start := time.Now()
+		next(c)
+		size := c.Writer.Size()
+		api.Instrumentation.Observe(Observation{
+			Resource: resource,
+			Action:   action,
+			Status:   c.Writer.Status(),
+			Duration: time.Since(start),
+			Size:     size,
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package api2go
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// The ExistenceChecker interface can be optionally implemented by a data
+// source to check many IDs with one lookup when API.ReferentialIntegrity is
+// set. Without it FindOne is called for every linked ID.
+type ExistenceChecker interface {
+	// MissingIDs returns those of ids which do not exist.
+	MissingIDs(ids []string, req Request) ([]string, error)
+}
+
+// linkageRef is a linked resource of a request document and the JSON
+// pointer to its resource identifier.
+type linkageRef struct {
+	typ, id, pointer string
+}
+
+// refs returns the resources linked by l with the type of relation, pointer
+// is the JSON pointer to l.
+func (l linkage) refs(relation relationship, pointer string) []linkageRef {
+	if l.isMany {
+		refs := make([]linkageRef, 0, len(l.many))
+		for i, ri := range l.many {
+			refs = append(refs, linkageRef{relation.typ, ri.ID,
+				pointer + "/" + strconv.Itoa(i)})
+		}
+		return refs
+	}
+	if l.one == nil {
+		return nil
+	}
+	return []linkageRef{{relation.typ, l.one.ID, pointer}}
+}
+
+// relationshipRefs returns the resources linked by the relationships of the
+// resource object ro of a create or update request.
+func (res *resource) relationshipRefs(ro *resourceObject) []linkageRef {
+	var refs []linkageRef
+	for _, relation := range res.info.relations {
+		rel, ok := ro.Relationships[relation.name]
+		if !ok {
+			continue
+		}
+		refs = append(refs, rel.Data.refs(*relation,
+			"/data/relationships/"+relation.name+"/data")...)
+	}
+	return refs
+}
+
+// checkIntegrity returns an HTTPError with a source pointer for every
+// resource of refs which does not exist, if API.ReferentialIntegrity is set.
+// Types without a resource added to the API are not checked.
+func (api *API) checkIntegrity(c *routeContext, refs []linkageRef) error {
+	if !api.ReferentialIntegrity || len(refs) == 0 {
+		return nil
+	}
+	byType := map[string][]string{}
+	for _, ref := range refs {
+		byType[ref.typ] = append(byType[ref.typ], ref.id)
+	}
+	missing := map[linkageRef]bool{}
+	for typ, ids := range byType {
+		res := api.resource(typ)
+		if res == nil {
+			continue
+		}
+		ids, err := res.missingIDs(c, ids)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			missing[linkageRef{typ: typ, id: id}] = true
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	status := api.MissingLinkageStatus
+	if status == 0 {
+		status = http.StatusNotFound
+	}
+	httpErr := NewHTTPError(nil, "Linked resources do not exist", status)
+	for _, ref := range refs {
+		if missing[linkageRef{typ: ref.typ, id: ref.id}] {
+			httpErr.AddSourceError(ref.pointer, fmt.Sprintf(
+				"The related %s %s does not exist", ref.typ, ref.id))
+		}
+	}
+	return httpErr
+}
+
+// resource returns the resource added with name, nil if there is none.
+func (api *API) resource(name string) *resource {
+	for i := range api.resources {
+		if api.resources[i].name == name {
+			return &api.resources[i]
+		}
+	}
+	return nil
+}
+
+// missingIDs returns those of ids which the data source of res does not
+// find.
+func (res *resource) missingIDs(c *routeContext, ids []string) ([]string,
+	error) {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	sort.Strings(unique)
+	if checker, ok := sourceAs[ExistenceChecker](res.source); ok {
+		req, span := sourceRequest(c, SpanMissingIDs)
+		missing, err := checker.MissingIDs(unique, req)
+		endSpan(span, err)
+		return missing, err
+	}
+	var missing []string
+	for _, id := range unique {
+		req, span := sourceRequest(c, SpanFindOne)
+		_, err := res.source.FindOne(id, req)
+		endSpan(span, err)
+		if e, ok := err.(HTTPError); ok && e.Status() == http.StatusNotFound {
+			missing = append(missing, id)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,71 @@
+package api2go_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/cention-sany/api2go"
+)
+
+func TestReferentialIntegrity(t *testing.T) {
+	router := NewHTTPRouter("/v1")
+	api := NewAPI("v1", NewStaticResolver(""))
+	api.ReferentialIntegrity = true
+	authors := &relationSource{}
+	api.AddResourceWithRouter(router, &post{}, newPostSource("a", "b"))
+	api.AddResourceWithRouter(router, &author{}, authors)
+
+	tests := []struct {
+		method, target, body string
+		code                 int
+		pointer              string
+	}{
+		{"PATCH", "/v1/authors/1/relationships/posts",
+			`{"data":[{"type":"posts","id":"1"},{"type":"posts","id":"9"}]}`,
+			http.StatusNotFound, "/data/1"},
+		{"POST", "/v1/authors/1/relationships/posts",
+			`{"data":[{"type":"posts","id":"8"}]}`,
+			http.StatusNotFound, "/data/0"},
+		{"POST", "/v1/authors", `{"data":{"type":"authors",
+			"relationships":{"posts":{"data":[{"type":"posts","id":"7"}]}}}}`,
+			http.StatusNotFound, "/data/relationships/posts/data/0"},
+		{"PATCH", "/v1/authors/1/relationships/posts",
+			`{"data":[{"type":"posts","id":"1"},{"type":"posts","id":"2"}]}`,
+			http.StatusNoContent, ""},
+		{"DELETE", "/v1/authors/1/relationships/posts",
+			`{"data":[{"type":"posts","id":"9"}]}`,
+			http.StatusNoContent, ""},
+	}
+	for _, tt := range tests {
+		rec := serve(router, tt.method, tt.target, tt.body)
+		if rec.Code != tt.code {
+			t.Errorf("Expect status %d for %s %s but got %d: %s", tt.code,
+				tt.method, tt.target, rec.Code, rec.Body)
+			continue
+		}
+		if tt.pointer != "" && !strings.Contains(rec.Body.String(),
+			`"pointer":"`+tt.pointer+`"`) {
+			t.Errorf("Expect pointer %s but got %s", tt.pointer, rec.Body)
+		}
+	}
+	if len(authors.calls) != 2 {
+		t.Errorf("Expect only the valid changes to reach the source but got "+
+			"%v.", authors.calls)
+	}
+
+	api.MissingLinkageStatus = http.StatusUnprocessableEntity
+	rec := serve(router, "PATCH", "/v1/authors/1/relationships/posts",
+		`{"data":[{"type":"posts","id":"9"}]}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expect status %d but got %d.",
+			http.StatusUnprocessableEntity, rec.Code)
+	}
+	api.ReferentialIntegrity = false
+	rec = serve(router, "PATCH", "/v1/authors/1/relationships/posts",
+		`{"data":[{"type":"posts","id":"9"}]}`)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Expect status %d without checks but got %d.",
+			http.StatusNoContent, rec.Code)
+	}
+}
@@ -1,5 +1,7 @@
 package api2go
 
+import "github.com/cention-sany/jsonapi"
+
 // The Identifier interface is necessary to give an element a unique ID.
 //
 // Note: The implementation of this interface is mandatory.
@@ -71,7 +73,31 @@ type UnmarshalToManyRelations interface {
 //
 // Deletes comments that belong to post with ID 1.
 // The DeleteToManyIDs method will be called.
+//
+// After each of them Update is called with the changed object. Its Responder
+// status is answered: 200 OK with the resulting resource linkage, 202
+// Accepted or 204 No Content. Errors of these methods are answered as well.
 type EditToManyRelations interface {
 	AddToManyIDs(name string, IDs []string) error
 	DeleteToManyIDs(name string, IDs []string) error
 }
+
+// The MarshalNode interface can be optionally implemented by a model to
+// marshal itself into a resource object without reflection, e.g. with the
+// method written by api2gogen -node. It is preferred over the jsonapi tags
+// for responses. The relationships hold resource linkage only, related
+// objects are not included unless the include parameter asks for them.
+// Links of LinksWithSI and RelationshipLinksWithSI, see DefaultLinks, are
+// added to the node.
+type MarshalNode interface {
+	MarshalJSONAPINode(si jsonapi.ServerInformation) *jsonapi.Node
+}
+
+// The UnmarshalNode interface can be optionally implemented by a model to
+// set itself from the resource object of a create or update request without
+// reflection. The attribute values of node are json.RawMessage, which
+// UnmarshalAttribute decodes, and its relationships are
+// *jsonapi.RelationshipOneNode or *jsonapi.RelationshipManyNode.
+type UnmarshalNode interface {
+	UnmarshalJSONAPINode(node *jsonapi.Node) error
+}
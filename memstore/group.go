@@ -0,0 +1,72 @@
+package memstore
+
+import (
+	"sync"
+
+	"github.com/cention-sany/api2go"
+)
+
+// Group connects the Stores of related resources. api2go serves the linked
+// route /users/1/sweets by calling FindAll of the sweets resource with the
+// query parameters usersID=1 and usersName=sweets. A Store of a Group
+// answers such a request with the objects linked by user 1.
+type Group struct {
+	mu     sync.RWMutex
+	stores map[string]*Store
+}
+
+// NewGroup creates an empty Group.
+func NewGroup() *Group {
+	return &Group{stores: map[string]*Store{}}
+}
+
+// Add creates a Store for prototype within the group, see New.
+func (g *Group) Add(prototype api2go.Identifier) *Store {
+	return g.AddStore(New(prototype))
+}
+
+// AddStore adds an existing Store to the group.
+func (g *Group) AddStore(s *Store) *Store {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	s.group = g
+	g.stores[s.Name()] = s
+	return s
+}
+
+// linked returns the IDs linked to s by the parent of a linked route, or nil
+// if req is no such request.
+func (g *Group) linked(s *Store, req api2go.Request) (map[string]bool,
+	error) {
+	if g == nil {
+		return nil, nil
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	for name, parent := range g.stores {
+		ids, ok := req.QueryParams[name+"ID"]
+		if !ok || len(ids) == 0 {
+			continue
+		}
+		rels := req.QueryParams[name+"Name"]
+		if len(rels) == 0 {
+			continue
+		}
+		parent.mu.RLock()
+		p, found := parent.objects[ids[0]]
+		var linked []string
+		if found {
+			linked, _ = parent.meta.relationIDs(p.Elem(), rels[0])
+		}
+		parent.mu.RUnlock()
+		if !found {
+			return nil, notFound(name, ids[0])
+		}
+		only := make(map[string]bool, len(linked))
+		for _, id := range linked {
+			only[id] = true
+		}
+		return only, nil
+	}
+	return nil, nil
+}
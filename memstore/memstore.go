@@ -0,0 +1,332 @@
+// Package memstore provides a generic, thread-safe in-memory data source
+// for api2go. It works with any model which satisfies api2go.Identifier and
+// has jsonapi tags, and is meant for prototypes and tests:
+//
+//	users := memstore.New(&model.User{})
+//	api.AddResource(rg, &model.User{}, users)
+//
+// Stores created by one Group answer the linked resource routes such as
+// /users/1/sweets, see Group.
+package memstore
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/cention-sany/api2go"
+)
+
+// IDGenerator returns a new unique ID for a created object.
+type IDGenerator func() string
+
+// NewSequence returns an IDGenerator counting up from 1.
+func NewSequence() IDGenerator {
+	var (
+		mu sync.Mutex
+		n  int
+	)
+	return func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		n++
+		return strconv.Itoa(n)
+	}
+}
+
+// Store is an in-memory data source for one resource type. It implements
+// api2go.CRUD, api2go.FindAll, api2go.PaginatedFindAll, api2go.FindMany and
+// changes relationships with api2go.RelationshipReplacer and
+// api2go.RelationshipEditor. It is safe for concurrent use.
+//
+// It implements api2go.ExistenceChecker as well, for the
+// API.ReferentialIntegrity checks of linkage to its resource.
+type Store struct {
+	mu      sync.RWMutex
+	typ     reflect.Type // struct type of the model
+	isPtr   bool         // whether the prototype is a pointer
+	meta    *modelMeta
+	objects map[string]reflect.Value // pointers to private copies
+	order   []string                 // insertion order of the IDs
+	newID   IDGenerator
+	group   *Group
+}
+
+// New creates a Store for prototype, which must be a struct or a pointer to
+// a struct, like the one given to api2go.API.AddResource. IDs are generated
+// by NewSequence.
+func New(prototype api2go.Identifier) *Store {
+	return NewWithGenerator(prototype, NewSequence())
+}
+
+// NewWithGenerator creates a Store which generates IDs with gen.
+func NewWithGenerator(prototype api2go.Identifier, gen IDGenerator) *Store {
+	t := reflect.TypeOf(prototype)
+	isPtr := t.Kind() == reflect.Ptr
+	if isPtr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		panic("memstore: prototype must be a struct or a struct pointer")
+	}
+	return &Store{
+		typ:     t,
+		isPtr:   isPtr,
+		meta:    newModelMeta(t),
+		objects: map[string]reflect.Value{},
+		newID:   gen,
+	}
+}
+
+// Name returns the resource name of the jsonapi primary tag.
+func (s *Store) Name() string {
+	return s.meta.name
+}
+
+func notFound(name, id string) error {
+	msg := fmt.Sprintf("%s with id %s not found", name, id)
+	return api2go.NewHTTPError(nil, msg, http.StatusNotFound)
+}
+
+// ptrOf returns a pointer to a copy of obj, which must be of the model type.
+func (s *Store) ptrOf(obj interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(obj)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return v, api2go.NewHTTPError(nil, "Invalid instance given",
+				http.StatusBadRequest)
+		}
+		v = v.Elem()
+	}
+	if v.Type() != s.typ {
+		return v, api2go.NewHTTPError(fmt.Errorf("memstore: got %T", obj),
+			"Invalid instance given", http.StatusBadRequest)
+	}
+	return deepCopy(v), nil
+}
+
+// deepCopy copies the struct v into a new pointer. Slices are copied as well
+// so that edits of a returned object never touch the stored one.
+func deepCopy(v reflect.Value) reflect.Value {
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	e := p.Elem()
+	for i := 0; i < e.NumField(); i++ {
+		f := e.Field(i)
+		if f.Kind() == reflect.Slice && !f.IsNil() && f.CanSet() {
+			c := reflect.MakeSlice(f.Type(), f.Len(), f.Len())
+			reflect.Copy(c, f)
+			f.Set(c)
+		}
+	}
+	return p
+}
+
+// out returns a copy of the stored pointer p in the form of the prototype.
+func (s *Store) out(p reflect.Value) interface{} {
+	c := deepCopy(p.Elem())
+	if s.isPtr {
+		return c.Interface()
+	}
+	return c.Elem().Interface()
+}
+
+// outSlice returns copies of ps as slice of the prototype type.
+func (s *Store) outSlice(ps []reflect.Value) interface{} {
+	t := s.typ
+	if s.isPtr {
+		t = reflect.PtrTo(t)
+	}
+	res := reflect.MakeSlice(reflect.SliceOf(t), 0, len(ps))
+	for _, p := range ps {
+		res = reflect.Append(res, reflect.ValueOf(s.out(p)))
+	}
+	return res.Interface()
+}
+
+// Get returns a copy of the object with id.
+func (s *Store) Get(id string) (interface{}, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.objects[id]
+	if !ok {
+		return nil, false
+	}
+	return s.out(p), true
+}
+
+// Len returns the number of stored objects.
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.objects)
+}
+
+// FindOne implements api2go.CRUD.
+func (s *Store) FindOne(id string, req api2go.Request) (api2go.Responder,
+	error) {
+	obj, ok := s.Get(id)
+	if !ok {
+		return nil, notFound(s.meta.name, id)
+	}
+	return &api2go.Response{Res: obj, Code: http.StatusOK}, nil
+}
+
+// Create implements api2go.CRUD. A client generated ID is kept, a 409
+// Conflict is returned if it is already taken.
+func (s *Store) Create(obj interface{}, req api2go.Request) (api2go.Responder,
+	error) {
+	p, err := s.ptrOf(obj)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := p.Interface().(api2go.Identifier).GetID()
+	if id == "" {
+		for id == "" || s.objects[id].IsValid() {
+			id = s.newID()
+		}
+		setter, ok := p.Interface().(api2go.UnmarshalIdentifier)
+		if !ok {
+			return nil, fmt.Errorf("memstore: %s must implement SetID",
+				s.typ)
+		}
+		if err := setter.SetID(id); err != nil {
+			return nil, err
+		}
+	} else if _, exists := s.objects[id]; exists {
+		return nil, api2go.NewHTTPError(nil,
+			fmt.Sprintf("%s with id %s already exists", s.meta.name, id),
+			http.StatusConflict)
+	}
+	s.objects[id] = p
+	s.order = append(s.order, id)
+	return &api2go.Response{Res: s.out(p), Code: http.StatusCreated}, nil
+}
+
+// Update implements api2go.CRUD and replaces the stored object.
+func (s *Store) Update(obj interface{}, req api2go.Request) (api2go.Responder,
+	error) {
+	p, err := s.ptrOf(obj)
+	if err != nil {
+		return nil, err
+	}
+	id := p.Interface().(api2go.Identifier).GetID()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.objects[id]; !ok {
+		return nil, notFound(s.meta.name, id)
+	}
+	s.objects[id] = p
+	return &api2go.Response{Res: s.out(p), Code: http.StatusOK}, nil
+}
+
+// Delete implements api2go.CRUD.
+func (s *Store) Delete(id string, req api2go.Request) (api2go.Responder,
+	error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.objects[id]; !ok {
+		return nil, notFound(s.meta.name, id)
+	}
+	delete(s.objects, id)
+	for i, o := range s.order {
+		if o == id {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+	return &api2go.Response{Code: http.StatusNoContent}, nil
+}
+
+// query returns the stored objects matching the filter, linkage and sort
+// parameters of req.
+func (s *Store) query(req api2go.Request) ([]reflect.Value, error) {
+	only, err := s.group.linked(s, req)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	filters, err := s.meta.filters(req.QueryParams)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]reflect.Value, 0, len(s.order))
+	for _, id := range s.order {
+		if only != nil && !only[id] {
+			continue
+		}
+		p := s.objects[id]
+		if s.meta.matches(p.Elem(), filters) {
+			res = append(res, p)
+		}
+	}
+	less, err := s.meta.sorter(req.QueryParams["sort"])
+	if err != nil {
+		return nil, err
+	}
+	if less != nil {
+		sort.SliceStable(res, func(i, j int) bool {
+			return less(res[i].Elem(), res[j].Elem())
+		})
+	}
+	return res, nil
+}
+
+// FindAll implements api2go.FindAll. It honours sort=a,-b and filter[attr]=
+// parameters, see the package documentation.
+func (s *Store) FindAll(req api2go.Request) (api2go.Responder, error) {
+	res, err := s.query(req)
+	if err != nil {
+		return nil, err
+	}
+	return &api2go.Response{Res: s.outSlice(res), Code: http.StatusOK}, nil
+}
+
+// FindMany implements api2go.FindMany and returns the objects in the order
+// of ids.
+func (s *Store) FindMany(ids []string, req api2go.Request) (api2go.Responder,
+	error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var res []reflect.Value
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if p, ok := s.objects[id]; ok && !seen[id] {
+			seen[id] = true
+			res = append(res, p)
+		}
+	}
+	return &api2go.Response{Res: s.outSlice(res), Code: http.StatusOK}, nil
+}
+
+// PaginatedFindAll implements api2go.PaginatedFindAll on top of FindAll with
+// api2go.OffsetPage.
+func (s *Store) PaginatedFindAll(req api2go.Request) (uint, api2go.Responder,
+	error) {
+	res, err := s.query(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	paged, offset, limit, err := api2go.OffsetPage(&req)
+	if err != nil {
+		return 0, nil, api2go.NewHTTPError(err, err.Error(),
+			http.StatusBadRequest)
+	}
+	total := uint(len(res))
+	if offset < 0 || offset > len(res) {
+		offset = len(res)
+	}
+	res = res[offset:]
+	// without page parameters or page[limit] all objects are returned
+	if paged && limit > 0 && limit < len(res) {
+		res = res[:limit]
+	}
+	return total, &api2go.Response{Res: s.outSlice(res), Code: http.StatusOK},
+		nil
+}
@@ -0,0 +1,209 @@
+package memstore_test
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/cention-sany/api2go"
+	"github.com/cention-sany/api2go/api2gotest"
+	"github.com/cention-sany/api2go/memstore"
+)
+
+type book struct {
+	ID    string `jsonapi:"primary,books"`
+	Title string `jsonapi:"attr,title"`
+	Year  int    `jsonapi:"attr,year"`
+}
+
+func (b book) GetID() string { return b.ID }
+
+func (b *book) SetID(id string) error {
+	b.ID = id
+	return nil
+}
+
+type author struct {
+	ID    string  `jsonapi:"primary,authors"`
+	Name  string  `jsonapi:"attr,name"`
+	Books []*book `jsonapi:"relation,books"`
+}
+
+func (a author) GetID() string { return a.ID }
+
+func (a *author) SetID(id string) error {
+	a.ID = id
+	return nil
+}
+
+func TestConformance(t *testing.T) {
+	api2gotest.Conformance{
+		Prototype: &book{},
+		Source:    memstore.New(&book{}),
+		New: func(i int) api2go.Identifier {
+			return &book{Title: fmt.Sprint("Book ", i), Year: 1950 + i}
+		},
+		Modify: func(obj api2go.Identifier) api2go.Identifier {
+			b := *obj.(*book)
+			b.Year++
+			return &b
+		},
+	}.Run(t)
+}
+
+func seed(t *testing.T, s *memstore.Store) {
+	for _, b := range []*book{
+		{Title: "Dune", Year: 1965},
+		{Title: "Solaris", Year: 1961},
+		{Title: "Ubik", Year: 1969},
+		{Title: "Neuromancer", Year: 1984},
+	} {
+		if _, err := s.Create(b, api2go.Request{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	books := memstore.New(&book{})
+	seed(t, books)
+	srv := api2gotest.NewServer("v1").AddResource(&book{}, books)
+
+	srv.GET(t, "/v1/books").Query("sort", "-year").Do().
+		Status(http.StatusOK).
+		DataIDs("4", "3", "1", "2")
+	srv.GET(t, "/v1/books").Query("sort", "title").Do().
+		DataIDs("1", "4", "2", "3")
+	srv.GET(t, "/v1/books").Query("filter[year]", "1961,1969").Do().
+		DataIDs("2", "3")
+	srv.GET(t, "/v1/books").Query("filter[colour]", "red").Do().
+		Status(http.StatusBadRequest)
+	srv.GET(t, "/v1/books").Query("sort", "year").
+		Query("page[offset]", "1").Query("page[limit]", "2").Do().
+		DataIDs("1", "3")
+	srv.GET(t, "/v1/books").Query("page[number]", "2").
+		Query("page[size]", "3").Do().
+		DataIDs("4")
+	srv.GET(t, "/v1/books").Query("filter[id]", "3,9,1").Do().
+		Status(http.StatusOK).
+		DataIDs("3", "1")
+}
+
+func TestPaginatedFindAll(t *testing.T) {
+	books := memstore.New(&book{})
+	seed(t, books)
+	for _, c := range []struct {
+		pagination map[string]string
+		count      int
+	}{
+		{nil, 4},
+		{map[string]string{"offset": "1"}, 3},
+		{map[string]string{"offset": "1", "limit": "2"}, 2},
+		{map[string]string{"number": "2", "size": "3"}, 1},
+	} {
+		total, rsp, err := books.PaginatedFindAll(api2go.Request{
+			Pagination: c.pagination})
+		if err != nil || total != 4 || len(rsp.Result().([]*book)) != c.count {
+			t.Errorf("Expect %d of 4 books for %v but got %v %v.", c.count,
+				c.pagination, rsp, err)
+		}
+	}
+}
+
+func TestCreateConflict(t *testing.T) {
+	books := memstore.New(&book{})
+	if _, err := books.Create(&book{ID: "x"}, api2go.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := books.Create(&book{ID: "x"}, api2go.Request{})
+	if e, ok := err.(api2go.HTTPError); !ok || e.Status() != http.StatusConflict {
+		t.Errorf("Expect 409 for a taken ID but got %v.", err)
+	}
+}
+
+func TestGroupLinked(t *testing.T) {
+	g := memstore.NewGroup()
+	books := g.Add(&book{})
+	authors := g.Add(&author{})
+	seed(t, books)
+	a := &author{Name: "Lem", Books: []*book{{ID: "2"}, {ID: "4"}}}
+	if _, err := authors.Create(a, api2go.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	srv := api2gotest.NewServer("v1").
+		AddResource(&book{}, books).
+		AddResource(&author{}, authors)
+	srv.GET(t, "/v1/authors/1/books").Do().
+		Status(http.StatusOK).
+		DataIDs("2", "4")
+}
+
+func TestEditRelationships(t *testing.T) {
+	authors := memstore.New(&author{})
+	if _, err := authors.Create(&author{Name: "Lem"}, api2go.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	srv := api2gotest.NewServer("v1").AddResource(&author{}, authors)
+	books := func() []string {
+		obj, _ := authors.Get("1")
+		ids := []string{}
+		for _, b := range obj.(*author).Books {
+			ids = append(ids, b.ID)
+		}
+		return ids
+	}
+
+	srv.POST(t, "/v1/authors/1/relationships/books").
+		Linkage("books", "1", "2").
+		Do().Status(http.StatusNoContent)
+	srv.POST(t, "/v1/authors/1/relationships/books").
+		Linkage("books", "2", "3").
+		Do().Status(http.StatusNoContent)
+	if ids := fmt.Sprint(books()); ids != "[1 2 3]" {
+		t.Errorf("Expect books [1 2 3] but got %s.", ids)
+	}
+	srv.DELETE(t, "/v1/authors/1/relationships/books").
+		Linkage("books", "2", "9").
+		Do().Status(http.StatusNoContent)
+	if ids := fmt.Sprint(books()); ids != "[1 3]" {
+		t.Errorf("Expect books [1 3] but got %s.", ids)
+	}
+	srv.PATCH(t, "/v1/authors/1/relationships/books").
+		Linkage("books", "4").
+		Do().Status(http.StatusNoContent)
+	if ids := fmt.Sprint(books()); ids != "[4]" {
+		t.Errorf("Expect books [4] but got %s.", ids)
+	}
+	missing, err := authors.MissingIDs([]string{"1", "2"}, api2go.Request{})
+	if err != nil || fmt.Sprint(missing) != "[2]" {
+		t.Errorf("Expect missing author [2] but got %v %v.", missing, err)
+	}
+	srv.PATCH(t, "/v1/authors/2/relationships/books").
+		Linkage("books", "4").
+		Do().Status(http.StatusNotFound)
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	books := memstore.New(&book{})
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rsp, err := books.Create(&book{Year: i}, api2go.Request{})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			b := rsp.Result().(*book)
+			b.Year++
+			books.Update(b, api2go.Request{})
+			books.FindAll(api2go.Request{})
+		}(i)
+	}
+	wg.Wait()
+	if books.Len() != 20 {
+		t.Errorf("Expect 20 books but got %d.", books.Len())
+	}
+}
@@ -0,0 +1,237 @@
+package memstore
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cention-sany/api2go"
+)
+
+var queryFilterRegex = regexp.MustCompile(`^filter\[([\w-]+)\]$`)
+
+// modelMeta caches the jsonapi tags of a model struct.
+type modelMeta struct {
+	name      string
+	attrs     map[string]int // attribute name to field index
+	relations map[string]int // relationship name to field index
+}
+
+func newModelMeta(t reflect.Type) *modelMeta {
+	m := &modelMeta{attrs: map[string]int{}, relations: map[string]int{}}
+	for i := 0; i < t.NumField(); i++ {
+		args := strings.Split(t.Field(i).Tag.Get("jsonapi"), ",")
+		if len(args) < 2 {
+			continue
+		}
+		switch args[0] {
+		case "primary":
+			m.name = args[1]
+		case "attr":
+			m.attrs[args[1]] = i
+		case "relation":
+			m.relations[args[1]] = i
+		}
+	}
+	if m.name == "" {
+		panic(fmt.Sprintf("memstore: %s has no jsonapi primary tag", t))
+	}
+	return m
+}
+
+// field returns the value of attribute name or the ID for "id".
+func (m *modelMeta) field(v reflect.Value, name string) (reflect.Value, bool) {
+	if name == "id" {
+		return reflect.ValueOf(v.Addr().Interface().(api2go.Identifier).GetID()),
+			true
+	}
+	i, ok := m.attrs[name]
+	if !ok {
+		return reflect.Value{}, false
+	}
+	return v.Field(i), true
+}
+
+type filter struct {
+	name   string
+	values []string
+}
+
+// filters parses filter[attr]=a,b parameters. Several values match any of
+// them.
+func (m *modelMeta) filters(params map[string][]string) ([]filter, error) {
+	var res []filter
+	for k, vs := range params {
+		match := queryFilterRegex.FindStringSubmatch(k)
+		if len(match) < 2 {
+			continue
+		}
+		if _, ok := m.attrs[match[1]]; !ok && match[1] != "id" {
+			return nil, api2go.NewHTTPError(nil,
+				fmt.Sprintf("Can not filter %s by unknown attribute %s", m.name,
+					match[1]), http.StatusBadRequest)
+		}
+		res = append(res, filter{name: match[1], values: vs})
+	}
+	return res, nil
+}
+
+func deref(v reflect.Value) (reflect.Value, bool) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+	return v, true
+}
+
+// matches tells if v passes all filters.
+func (m *modelMeta) matches(v reflect.Value, filters []filter) bool {
+	for _, f := range filters {
+		fv, _ := m.field(v, f.name)
+		fv, ok := deref(fv)
+		s := ""
+		if ok {
+			s = fmt.Sprint(fv.Interface())
+		}
+		found := false
+		for _, want := range f.values {
+			if s == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// compare orders two values of the same kind, nil pointers first.
+func compare(a, b reflect.Value) int {
+	a, aok := deref(a)
+	b, bok := deref(b)
+	switch {
+	case !aok && !bok:
+		return 0
+	case !aok:
+		return -1
+	case !bok:
+		return 1
+	}
+	if ta, ok := a.Interface().(time.Time); ok {
+		tb := b.Interface().(time.Time)
+		switch {
+		case ta.Before(tb):
+			return -1
+		case ta.After(tb):
+			return 1
+		}
+		return 0
+	}
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		return sign(float64(a.Int()) - float64(b.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		return sign(float64(a.Uint()) - float64(b.Uint()))
+	case reflect.Float32, reflect.Float64:
+		return sign(a.Float() - b.Float())
+	case reflect.Bool:
+		if a.Bool() == b.Bool() {
+			return 0
+		} else if b.Bool() {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+}
+
+func sign(f float64) int {
+	switch {
+	case f < 0:
+		return -1
+	case f > 0:
+		return 1
+	}
+	return 0
+}
+
+// sorter returns the less function for sort fields like "-title", or nil
+// if there are none.
+func (m *modelMeta) sorter(fields []string) (func(a, b reflect.Value) bool,
+	error) {
+	type key struct {
+		name string
+		desc bool
+	}
+	var keys []key
+	for _, f := range fields {
+		if f == "" {
+			continue
+		}
+		k := key{name: strings.TrimPrefix(f, "-"), desc: strings.HasPrefix(f, "-")}
+		if _, ok := m.attrs[k.name]; !ok && k.name != "id" {
+			return nil, api2go.NewHTTPError(nil,
+				fmt.Sprintf("Can not sort %s by unknown attribute %s", m.name,
+					k.name), http.StatusBadRequest)
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return func(a, b reflect.Value) bool {
+		for _, k := range keys {
+			fa, _ := m.field(a, k.name)
+			fb, _ := m.field(b, k.name)
+			c := compare(fa, fb)
+			if k.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	}, nil
+}
+
+// relationIDs returns the IDs linked by relationship name of v.
+func (m *modelMeta) relationIDs(v reflect.Value, name string) ([]string,
+	bool) {
+	i, ok := m.relations[name]
+	if !ok {
+		return nil, false
+	}
+	f := v.Field(i)
+	var ids []string
+	add := func(e reflect.Value) {
+		if e.Kind() == reflect.Ptr && e.IsNil() {
+			return
+		}
+		if e.Kind() != reflect.Ptr {
+			p := reflect.New(e.Type())
+			p.Elem().Set(e)
+			e = p
+		}
+		if o, ok := e.Interface().(api2go.Identifier); ok {
+			ids = append(ids, o.GetID())
+		}
+	}
+	if f.Kind() == reflect.Slice {
+		for j := 0; j < f.Len(); j++ {
+			add(f.Index(j))
+		}
+	} else {
+		add(f)
+	}
+	return ids, true
+}
@@ -0,0 +1,147 @@
+package memstore
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/cention-sany/api2go"
+)
+
+// ReplaceRelationship implements api2go.RelationshipReplacer and sets the
+// relationship name of the object with id to ids.
+func (s *Store) ReplaceRelationship(id, name string, ids []string,
+	req api2go.Request) (api2go.Responder, error) {
+	return s.editRelation(id, name, func([]string) []string {
+		return ids
+	})
+}
+
+// AddToRelationship implements api2go.RelationshipEditor. IDs which are
+// already linked are kept once.
+func (s *Store) AddToRelationship(id, name string, ids []string,
+	req api2go.Request) (api2go.Responder, error) {
+	return s.editRelation(id, name, func(old []string) []string {
+		seen := map[string]bool{}
+		for _, o := range old {
+			seen[o] = true
+		}
+		for _, n := range ids {
+			if !seen[n] {
+				seen[n] = true
+				old = append(old, n)
+			}
+		}
+		return old
+	})
+}
+
+// RemoveFromRelationship implements api2go.RelationshipEditor.
+func (s *Store) RemoveFromRelationship(id, name string, ids []string,
+	req api2go.Request) (api2go.Responder, error) {
+	return s.editRelation(id, name, func(old []string) []string {
+		obsolete := map[string]bool{}
+		for _, o := range ids {
+			obsolete[o] = true
+		}
+		kept := []string{}
+		for _, o := range old {
+			if !obsolete[o] {
+				kept = append(kept, o)
+			}
+		}
+		return kept
+	})
+}
+
+// MissingIDs implements api2go.ExistenceChecker.
+func (s *Store) MissingIDs(ids []string, req api2go.Request) ([]string,
+	error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var missing []string
+	for _, id := range ids {
+		if _, ok := s.objects[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+// editRelation replaces the IDs of relationship name of the stored object
+// with id by the result of edit, under the lock of the store.
+func (s *Store) editRelation(id, name string,
+	edit func(old []string) []string) (api2go.Responder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.objects[id]
+	if !ok {
+		return nil, notFound(s.meta.name, id)
+	}
+	i, ok := s.meta.relations[name]
+	if !ok {
+		return nil, api2go.NewHTTPError(nil,
+			fmt.Sprintf("%s has no relationship %s", s.meta.name, name),
+			http.StatusNotFound)
+	}
+	old, _ := s.meta.relationIDs(p.Elem(), name)
+	// edit a copy so that a failure leaves the stored object untouched
+	c := deepCopy(p.Elem())
+	if err := setRelationIDs(c.Elem().Field(i), edit(old)); err != nil {
+		return nil, err
+	}
+	s.objects[id] = c
+	return &api2go.Response{Code: http.StatusNoContent}, nil
+}
+
+// setRelationIDs sets the relationship field f to new related objects with
+// ids. A to-one field takes none or one ID.
+func setRelationIDs(f reflect.Value, ids []string) error {
+	if f.Kind() == reflect.Slice {
+		s := reflect.MakeSlice(f.Type(), 0, len(ids))
+		for _, id := range ids {
+			e, err := related(f.Type().Elem(), id)
+			if err != nil {
+				return err
+			}
+			s = reflect.Append(s, e)
+		}
+		f.Set(s)
+		return nil
+	}
+	switch len(ids) {
+	case 0:
+		f.Set(reflect.Zero(f.Type()))
+		return nil
+	case 1:
+		e, err := related(f.Type(), ids[0])
+		if err != nil {
+			return err
+		}
+		f.Set(e)
+		return nil
+	}
+	return api2go.NewHTTPError(nil, "A to-one relationship takes one linkage",
+		http.StatusBadRequest)
+}
+
+// related returns a new object of type t, a struct or a struct pointer, with
+// id.
+func related(t reflect.Type, id string) (reflect.Value, error) {
+	isPtr := t.Kind() == reflect.Ptr
+	if isPtr {
+		t = t.Elem()
+	}
+	p := reflect.New(t)
+	setter, ok := p.Interface().(api2go.UnmarshalIdentifier)
+	if !ok {
+		return p, fmt.Errorf("memstore: %s must implement SetID", t)
+	}
+	if err := setter.SetID(id); err != nil {
+		return p, err
+	}
+	if isPtr {
+		return p, nil
+	}
+	return p.Elem(), nil
+}
@@ -0,0 +1,217 @@
+package api2go
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	// DefaultLatencyBuckets are the upper bounds in seconds of the latency
+	// histogram of NewMetrics.
+	DefaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1,
+		2.5, 5, 10}
+	// DefaultSizeBuckets are the upper bounds in bytes of the payload size
+	// histogram of NewMetrics.
+	DefaultSizeBuckets = []float64{100, 1000, 10000, 100000, 1000000,
+		10000000}
+)
+
+type metricKey struct {
+	resource, action string
+}
+
+type counterKey struct {
+	metricKey
+	class string
+}
+
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, b := range h.buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// Metrics is an in-process Instrumentation which keeps request counters and
+// histograms per resource and action and exposes them in the Prometheus text
+// format.
+type Metrics struct {
+	mu       sync.Mutex
+	latBkts  []float64
+	sizeBkts []float64
+	requests map[counterKey]uint64
+	latency  map[metricKey]*histogram
+	size     map[metricKey]*histogram
+}
+
+// NewMetrics creates Metrics with DefaultLatencyBuckets and
+// DefaultSizeBuckets.
+func NewMetrics() *Metrics {
+	return NewMetricsWithBuckets(DefaultLatencyBuckets, DefaultSizeBuckets)
+}
+
+// NewMetricsWithBuckets creates Metrics with custom histogram buckets. Both
+// bucket lists must be sorted in increasing order.
+func NewMetricsWithBuckets(latency, size []float64) *Metrics {
+	return &Metrics{
+		latBkts:  latency,
+		sizeBkts: size,
+		requests: make(map[counterKey]uint64),
+		latency:  make(map[metricKey]*histogram),
+		size:     make(map[metricKey]*histogram),
+	}
+}
+
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// Observe implements Instrumentation.
+func (m *Metrics) Observe(o Observation) {
+	k := metricKey{resource: o.Resource, action: o.Action}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[counterKey{metricKey: k, class: statusClass(o.Status)}]++
+	lat, ok := m.latency[k]
+	if !ok {
+		lat = newHistogram(m.latBkts)
+		m.latency[k] = lat
+	}
+	lat.observe(o.Duration.Seconds())
+	size, ok := m.size[k]
+	if !ok {
+		size = newHistogram(m.sizeBkts)
+		m.size[k] = size
+	}
+	size.observe(float64(o.Size))
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func labels(k metricKey) string {
+	return fmt.Sprintf("resource=%q,action=%q", k.resource, k.action)
+}
+
+func sortedKeys(m map[metricKey]*histogram) []metricKey {
+	keys := make([]metricKey, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].resource != keys[j].resource {
+			return keys[i].resource < keys[j].resource
+		}
+		return keys[i].action < keys[j].action
+	})
+	return keys
+}
+
+func writeHistogram(w io.Writer, name, help string,
+	m map[metricKey]*histogram) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	for _, k := range sortedKeys(m) {
+		h := m[k]
+		l := labels(k)
+		for i, b := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", name, l,
+				formatFloat(b), h.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, l, h.count)
+		fmt.Fprintf(w, "%s_sum{%s} %s\n", name, l, formatFloat(h.sum))
+		fmt.Fprintf(w, "%s_count{%s} %d\n", name, l, h.count)
+	}
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: bufio.NewWriter(w)}
+	m.mu.Lock()
+	keys := make([]counterKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].resource != keys[j].resource {
+			return keys[i].resource < keys[j].resource
+		}
+		if keys[i].action != keys[j].action {
+			return keys[i].action < keys[j].action
+		}
+		return keys[i].class < keys[j].class
+	})
+	fmt.Fprint(cw, "# HELP api2go_requests_total Number of handled requests.\n")
+	fmt.Fprint(cw, "# TYPE api2go_requests_total counter\n")
+	for _, k := range keys {
+		fmt.Fprintf(cw, "api2go_requests_total{%s,status_class=%q} %d\n",
+			labels(k.metricKey), k.class, m.requests[k])
+	}
+	writeHistogram(cw, "api2go_request_duration_seconds",
+		"Latency of handled requests in seconds.", m.latency)
+	writeHistogram(cw, "api2go_response_size_bytes",
+		"Size of written response bodies in bytes.", m.size)
+	m.mu.Unlock()
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+	return cw.n, cw.w.Flush()
+}
+
+// Handler returns an optional gin handler that serves the metrics, e.g.
+//
+//	r.GET("/metrics", metrics.Handler())
+func (m *Metrics) Handler() gin.HandlerFunc {
+	return gin.WrapH(m)
+}
+
+// ServeHTTP serves the metrics on net/http, e.g.
+//
+//	http.Handle("/metrics", metrics)
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", metricsContentType)
+	w.WriteHeader(http.StatusOK)
+	m.WriteTo(w)
+}
+
+type countWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
@@ -0,0 +1,48 @@
+package api2go_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/cention-sany/api2go"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	metrics := NewMetrics()
+	api := NewAPI("v1", NewStaticResolver(""))
+	api.Instrumentation = metrics
+	api.AddResource(r.Group("/v1"), &post{}, newPostSource("hello"))
+	r.GET("/metrics", metrics.Handler())
+
+	for _, path := range []string{"/v1/posts", "/v1/posts/1", "/v1/posts/9"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	for _, path := range []string{"/v1/posts", "/v1/posts/1"} {
+		req, _ := http.NewRequest("HEAD", path, nil)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	r.ServeHTTP(rec, req)
+	out := rec.Body.String()
+	for _, exp := range []string{
+		`api2go_requests_total{resource="posts",action="index",status_class="2xx"} 1`,
+		`api2go_requests_total{resource="posts",action="read",status_class="2xx"} 1`,
+		`api2go_requests_total{resource="posts",action="read",status_class="4xx"} 1`,
+		`api2go_request_duration_seconds_count{resource="posts",action="read"} 2`,
+		`api2go_response_size_bytes_bucket{resource="posts",action="index",le="+Inf"} 1`,
+		`api2go_requests_total{resource="posts",action="head-index",status_class="2xx"} 1`,
+		`api2go_requests_total{resource="posts",action="head-read",status_class="2xx"} 1`,
+	} {
+		if !strings.Contains(out, exp) {
+			t.Errorf("Expect %q in metrics output:\n%s", exp, out)
+		}
+	}
+}
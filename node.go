@@ -0,0 +1,167 @@
+package api2go
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+
+	ja "github.com/cention-sany/jsonapi"
+)
+
+// linksWithSI is implemented by models with links, see DefaultLinks.
+type linksWithSI interface {
+	LinksWithSI(si ja.ServerInformation) *ja.Links
+}
+
+// relationshipLinksWithSI is implemented by models with relationship links,
+// see DefaultLinks.
+type relationshipLinksWithSI interface {
+	RelationshipLinksWithSI(r string, si ja.ServerInformation) *ja.Links
+}
+
+// nodeOf returns the resource object of m with its links.
+func nodeOf(m MarshalNode, si ja.ServerInformation) *ja.Node {
+	return withLinks(m.MarshalJSONAPINode(si), m, si)
+}
+
+// withLinks sets the links of node, the resource object of the model v.
+func withLinks(node *ja.Node, v interface{},
+	si ja.ServerInformation) *ja.Node {
+	if l, ok := v.(linksWithSI); ok {
+		node.Links = l.LinksWithSI(si)
+	}
+	if l, ok := v.(relationshipLinksWithSI); ok {
+		for name, rel := range node.Relationships {
+			switch rel := rel.(type) {
+			case *ja.RelationshipOneNode:
+				rel.Links = l.RelationshipLinksWithSI(name, si)
+			case *ja.RelationshipManyNode:
+				rel.Links = l.RelationshipLinksWithSI(name, si)
+			}
+		}
+	}
+	return node
+}
+
+// marshalOne marshals v into a document with one resource object, with
+// MarshalNode if v implements it and else with the jsonapi tags. Models
+// without a typeInfo are left to the jsonapi library.
+func marshalOne(v interface{}, si ja.ServerInformation) (*ja.OnePayload,
+	error) {
+	if m, ok := v.(MarshalNode); ok {
+		return &ja.OnePayload{Data: nodeOf(m, si)}, nil
+	}
+	ti := taggedInfo(reflect.TypeOf(v))
+	if ti == nil {
+		return ja.MarshalOneWithSI(v, si)
+	}
+	m := newTagMarshaler(si)
+	node, err := m.node(ti, v)
+	if err != nil {
+		return nil, err
+	}
+	return &ja.OnePayload{Data: node, Included: m.included}, nil
+}
+
+// marshalMany marshals vs into a document with many resource objects, with
+// MarshalNode for those of vs which implement it and else with the jsonapi
+// tags. If one of vs has no typeInfo, all are left to the jsonapi library.
+func marshalMany(vs []interface{}, si ja.ServerInformation) (*ja.ManyPayload,
+	error) {
+	var (
+		nodes = make([]*ja.Node, 0, len(vs))
+		m     = newTagMarshaler(si)
+		t     reflect.Type
+		ti    *typeInfo
+	)
+	for _, v := range vs {
+		if mn, ok := v.(MarshalNode); ok {
+			nodes = append(nodes, nodeOf(mn, si))
+			continue
+		}
+		if vt := reflect.TypeOf(v); vt != t || ti == nil {
+			t, ti = vt, taggedInfo(vt)
+		}
+		if ti == nil {
+			return ja.MarshalManyWithSI(vs, si)
+		}
+		node, err := m.node(ti, v)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return &ja.ManyPayload{Data: nodes, Included: m.included}, nil
+}
+
+// unmarshal sets obj from the resource object ro of the request document
+// body, with UnmarshalNode if obj implements it and else with the jsonapi
+// tags. Models without a typeInfo are left to the jsonapi library, which
+// decodes body again.
+func unmarshal(body []byte, ro *resourceObject, obj interface{}) error {
+	if u, ok := obj.(UnmarshalNode); ok {
+		return u.UnmarshalJSONAPINode(ro.node())
+	}
+	var ti *typeInfo
+	if v := reflect.ValueOf(obj); v.Kind() == reflect.Ptr && !v.IsNil() {
+		ti = taggedInfo(v.Type())
+	}
+	if ti == nil {
+		return ja.UnmarshalPayload(bytes.NewReader(body), obj)
+	}
+	return ti.unmarshalNode(ro.node(), reflect.ValueOf(obj).Elem())
+}
+
+// node returns the resource object as node, with json.RawMessage attribute
+// values.
+func (ro *resourceObject) node() *ja.Node {
+	node := &ja.Node{
+		ID:            ro.id(),
+		ClientID:      ro.ClientID,
+		Attributes:    make(map[string]interface{}, len(ro.Attributes)),
+		Relationships: make(map[string]interface{}, len(ro.Relationships)),
+	}
+	if ro.Type != nil {
+		node.Type = *ro.Type
+	}
+	for name, raw := range ro.Attributes {
+		node.Attributes[name] = raw
+	}
+	for name, rel := range ro.Relationships {
+		l := rel.Data
+		if !l.present {
+			continue
+		}
+		if l.isMany {
+			many := &ja.RelationshipManyNode{Data: make([]*ja.Node, 0,
+				len(l.many))}
+			for _, ri := range l.many {
+				many.Data = append(many.Data, &ja.Node{Type: ri.Type,
+					ID: ri.ID})
+			}
+			node.Relationships[name] = many
+			continue
+		}
+		one := &ja.RelationshipOneNode{}
+		if l.one != nil {
+			one.Data = &ja.Node{Type: l.one.Type, ID: l.one.ID}
+		}
+		node.Relationships[name] = one
+	}
+	return node
+}
+
+// UnmarshalAttribute stores the attribute value v of a resource object in
+// dst, a pointer like the one json.Unmarshal takes. v is a json.RawMessage
+// for the nodes passed to UnmarshalNode, any other value is converted with
+// encoding/json.
+func UnmarshalAttribute(v interface{}, dst interface{}) error {
+	raw, ok := v.(json.RawMessage)
+	if !ok {
+		var err error
+		if raw, err = json.Marshal(v); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(raw, dst)
+}
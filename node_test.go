@@ -0,0 +1,98 @@
+package api2go_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/cention-sany/api2go"
+	"github.com/cention-sany/jsonapi"
+)
+
+// gadget marshals and unmarshals itself, its tags are never read.
+type gadget struct {
+	ID     string `jsonapi:"primary,gadgets"`
+	Name   string `jsonapi:"attr,tagged"`
+	Parts  int
+	Nodes  int
+	Parsed bool
+}
+
+func (g gadget) GetID() string { return g.ID }
+
+func (g *gadget) SetID(id string) error {
+	g.ID = id
+	return nil
+}
+
+func (g gadget) MarshalJSONAPINode(si jsonapi.ServerInformation) *jsonapi.Node {
+	return &jsonapi.Node{
+		Type:          "gadgets",
+		ID:            g.ID,
+		Attributes:    map[string]interface{}{"name": g.Name, "parts": g.Parts},
+		Relationships: map[string]interface{}{},
+	}
+}
+
+func (g *gadget) UnmarshalJSONAPINode(node *jsonapi.Node) error {
+	g.Parsed = true
+	if err := UnmarshalAttribute(node.Attributes["name"], &g.Name); err != nil {
+		return err
+	}
+	return UnmarshalAttribute(node.Attributes["parts"], &g.Parts)
+}
+
+type gadgetSource struct {
+	created *gadget
+}
+
+func (s *gadgetSource) FindOne(id string, req Request) (Responder, error) {
+	return &Response{Res: &gadget{ID: id, Name: "lamp", Parts: 3},
+		Code: http.StatusOK}, nil
+}
+
+func (s *gadgetSource) Create(obj interface{}, req Request) (Responder,
+	error) {
+	s.created = obj.(*gadget)
+	s.created.ID = "2"
+	return &Response{Res: s.created, Code: http.StatusCreated}, nil
+}
+
+func (s *gadgetSource) Delete(id string, req Request) (Responder, error) {
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+func (s *gadgetSource) Update(obj interface{}, req Request) (Responder,
+	error) {
+	return &Response{Res: obj, Code: http.StatusOK}, nil
+}
+
+func TestMarshalNode(t *testing.T) {
+	router := NewHTTPRouter("/v1")
+	api := NewAPI("v1", NewStaticResolver(""))
+	source := &gadgetSource{}
+	api.AddResourceWithRouter(router, &gadget{}, source)
+
+	rec := serve(router, "GET", "/v1/gadgets/1", "")
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, `"parts":3`) ||
+		strings.Contains(body, "tagged") {
+		t.Errorf("Expect the node of MarshalJSONAPINode but got %d: %s",
+			rec.Code, body)
+	}
+
+	rec = serve(router, "GET", "/v1/gadgets/1?fields[gadgets]=name", "")
+	if body := rec.Body.String(); rec.Code != http.StatusOK ||
+		strings.Contains(body, "parts") {
+		t.Errorf("Expect the sparse fieldset but got %d: %s", rec.Code, body)
+	}
+
+	rec = serve(router, "POST", "/v1/gadgets", `{"data":{"type":"gadgets",`+
+		`"attributes":{"name":"desk","parts":4}}}`)
+	if rec.Code != http.StatusCreated || source.created == nil ||
+		!source.created.Parsed || source.created.Name != "desk" ||
+		source.created.Parts != 4 {
+		t.Errorf("Expect the node of UnmarshalJSONAPINode but got %d: %s, %+v",
+			rec.Code, rec.Body, source.created)
+	}
+}
@@ -0,0 +1,47 @@
+package api2go
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+// PanicHandler can be set on API to report a panic recovered from one of the
+// resource handlers elsewhere, e.g. to an error tracker. It is called after
+// the stack has been logged and before the error document is written.
+type PanicHandler func(r *http.Request, recovered interface{}, stack []byte)
+
+func (api *API) logger() *log.Logger {
+	if api.Logger != nil {
+		return api.Logger
+	}
+	return log.Default()
+}
+
+// recovery wraps every handler of addResource. It turns a panic inside a data
+// source into a 500 JSON:API error document instead of letting it escape to
+// the router.
+func (api *API) recovery(next handler) handler {
+	return func(c *routeContext) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			stack := debug.Stack()
+			api.logger().Printf("api2go: panic recovered: %v\n%s", r, stack)
+			if api.PanicHandler != nil {
+				api.PanicHandler(c.Request, r, stack)
+			}
+			if c.Writer.Written() {
+				// too late for an error document, headers are already sent
+				return
+			}
+			writeError(c.Writer, NewHTTPError(fmt.Errorf("panic: %v", r),
+				http.StatusText(http.StatusInternalServerError),
+				http.StatusInternalServerError))
+		}()
+		next(c)
+	}
+}
@@ -0,0 +1,78 @@
+package api2go_test
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/cention-sany/api2go"
+	"github.com/gin-gonic/gin"
+)
+
+type panicky struct {
+	ID string `jsonapi:"primary,panickies"`
+}
+
+func (p panicky) GetID() string { return p.ID }
+
+type panickySource struct{}
+
+func (panickySource) FindOne(id string, req Request) (Responder, error) {
+	panic("boom")
+}
+
+func (panickySource) Create(obj interface{}, req Request) (Responder, error) {
+	panic("boom")
+}
+
+func (panickySource) Delete(id string, req Request) (Responder, error) {
+	panic("boom")
+}
+
+func (panickySource) Update(obj interface{}, req Request) (Responder, error) {
+	panic("boom")
+}
+
+func TestPanicRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var (
+		logs      bytes.Buffer
+		recovered interface{}
+	)
+	r := gin.New()
+	api := NewAPI("v1", NewStaticResolver(""))
+	api.Logger = log.New(&logs, "", 0)
+	api.PanicHandler = func(r *http.Request, v interface{}, stack []byte) {
+		recovered = v
+	}
+	api.AddResource(r.Group("/v1"), panicky{}, panickySource{})
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/panickies/1", nil)
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expect status %d but got %d.", http.StatusInternalServerError,
+			rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/vnd.api+json" {
+		t.Errorf("Expect JSON:API content type but got %q.", ct)
+	}
+	if !strings.Contains(rec.Body.String(), `"errors"`) {
+		t.Errorf("Expect an error document but got %s.", rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "boom") {
+		t.Error("Panic value must not be sent to the client.")
+	}
+	if recovered != "boom" {
+		t.Errorf("Expect PanicHandler to get the panic value but got %v.",
+			recovered)
+	}
+	if !strings.Contains(logs.String(), "boom") ||
+		!strings.Contains(logs.String(), "goroutine") {
+		t.Errorf("Expect panic and stack in log but got %q.", logs.String())
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/cention-sany/jsonapi"
 )
@@ -13,29 +14,88 @@ const (
 	annotationSeperator = ","
 	annotationPrimary   = "primary"
 	annotationRelation  = "relation"
+	annotationClientID  = "client-id"
+	annotationOmitEmpty = "omitempty"
+	annotationISO8601   = "iso8601"
 	defRelSize          = 4
 )
 
 type relationship struct {
 	typ, name string
 	isMany    bool
+	index     int // of the struct field
+	omitEmpty bool
 }
 
-type nodeRelations struct {
-	typ       string
-	relations []*relationship
+// attributeField is a struct field tagged as attribute.
+type attributeField struct {
+	name      string
+	index     int
+	omitEmpty bool
+	iso8601   bool
 }
 
-func findRelations(t reflect.Type, f map[string]bool) (*nodeRelations, error) {
-	var (
-		er   error
-		node nodeRelations
-	)
+// typeInfo is the metadata of a resource struct type read from its jsonapi
+// tags. It is built once per type and shared by all requests. It serves the
+// routes, the attribute access, the sparse fieldsets and the marshalling by
+// tags, see tags.go.
+type typeInfo struct {
+	typ        reflect.Type // the struct type
+	name       string       // the primary type
+	id         int          // index of the primary field
+	clientID   int          // index of the client-id field, -1 if none
+	attributes map[string]bool
+	fields     []attributeField
+	relations  []*relationship
+	access     attributeAccess
+}
+
+// typeInfos caches the *typeInfo of every reflect.Type seen.
+var typeInfos sync.Map
+
+// cacheTypeInfos can be switched off by the benchmarks to read the tags on
+// every use, as before the cache: typeInfoOf reads them again and the models
+// are marshalled by the jsonapi library.
+var cacheTypeInfos = true
+
+// typeInfoOf returns the metadata of t, which is a struct type or a pointer
+// or slice of it. It is read from the tags on first use only.
+func typeInfoOf(t reflect.Type) (*typeInfo, error) {
+	if !cacheTypeInfos {
+		return newTypeInfo(t)
+	}
+	if ti, ok := typeInfos.Load(t); ok {
+		return ti.(*typeInfo), nil
+	}
+	ti, err := newTypeInfo(t)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := typeInfos.LoadOrStore(t, ti)
+	return actual.(*typeInfo), nil
+}
+
+// newTypeInfo reads the metadata of t from its tags.
+func newTypeInfo(t reflect.Type) (*typeInfo, error) {
 	k := t.Kind()
 	if k == reflect.Ptr || k == reflect.Slice {
 		t = t.Elem()
 	}
-	node.relations = make([]*relationship, 0, defRelSize)
+	if t.Kind() != reflect.Struct {
+		return nil, errors.New("api2go: need a struct type")
+	}
+	ti := &typeInfo{
+		typ:        t,
+		id:         -1,
+		clientID:   -1,
+		attributes: map[string]bool{},
+		relations:  make([]*relationship, 0, defRelSize),
+		access: attributeAccess{
+			readOnly:   map[string]bool{},
+			writeOnly:  map[string]bool{},
+			createOnly: map[string]bool{},
+		},
+	}
 	for i := 0; i < t.NumField(); i++ {
 		structField := t.Field(i)
 		tag := structField.Tag.Get(annotationJSONAPI)
@@ -43,41 +103,69 @@ func findRelations(t reflect.Type, f map[string]bool) (*nodeRelations, error) {
 			continue
 		}
 		args := strings.Split(tag, annotationSeperator)
-		if len(args) < 1 {
-			er = jsonapi.ErrBadJSONAPIStructTag
-			break
+		switch args[0] {
+		case annotationPrimary, annotationAttribute, annotationRelation:
+			if len(args) < 2 {
+				return nil, jsonapi.ErrBadJSONAPIStructTag
+			}
 		}
-		annotation := args[0]
-		if annotation == annotationPrimary {
-			if f[args[1]] {
-				break
+		switch args[0] {
+		case annotationPrimary:
+			if ti.name == "" {
+				ti.name = args[1]
+				ti.id = i
+			}
+		case annotationClientID:
+			ti.clientID = i
+		case annotationAttribute:
+			ti.attributes[args[1]] = true
+			ti.access.add(args[1], args[2:])
+			field := attributeField{name: args[1], index: i}
+			for _, option := range args[2:] {
+				switch option {
+				case annotationOmitEmpty:
+					field.omitEmpty = true
+				case annotationISO8601:
+					field.iso8601 = true
+				}
 			}
-			node.typ = args[1]
-			f[node.typ] = true
-		} else if annotation == annotationRelation {
+			ti.fields = append(ti.fields, field)
+		case annotationRelation:
 			tt := structField.Type
 			rel := &relationship{
-				name:   args[1],
-				isMany: tt.Kind() == reflect.Slice,
+				name:      args[1],
+				isMany:    tt.Kind() == reflect.Slice,
+				index:     i,
+				omitEmpty: len(args) > 2 && args[2] == annotationOmitEmpty,
 			}
 			if rel.isMany {
 				tt = tt.Elem()
 			}
-			// purpose ignore error here as without it can still work
 			relationshipType, err := findPrimary(tt)
 			if err != nil {
 				return nil, err
 			}
 			rel.typ = relationshipType
-			node.relations = append(node.relations, rel)
+			ti.relations = append(ti.relations, rel)
 		}
 	}
-	if er != nil {
-		return nil, er
-	} else if node.typ == "" {
+	if ti.name == "" {
 		return nil, errors.New("api2go: need primary")
 	}
-	return &node, nil
+	return ti, nil
+}
+
+// hasField tells if name is an attribute or relationship of the type.
+func (ti *typeInfo) hasField(name string) bool {
+	if ti.attributes[name] {
+		return true
+	}
+	for _, r := range ti.relations {
+		if r.name == name {
+			return true
+		}
+	}
+	return false
 }
 
 func findPrimary(t reflect.Type) (string, error) {
@@ -0,0 +1,126 @@
+package api2go_test
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/cention-sany/api2go"
+)
+
+type accountListSource struct {
+	accountSource
+	list []*account
+}
+
+func (s *accountListSource) FindAll(req Request) (Responder, error) {
+	return &Response{Res: s.list, Code: http.StatusOK}, nil
+}
+
+func newAccountAPI(n int) http.Handler {
+	return newAccountAPIWithCodec(n, nil)
+}
+
+// newAccountAPIWithCodec builds the API of newAccountAPI with codec, nil for
+// the default.
+func newAccountAPIWithCodec(n int, codec Codec) http.Handler {
+	router := NewHTTPRouter("/v1")
+	api := NewAPI("v1", NewStaticResolver(""))
+	api.Codec = codec
+	source := &accountListSource{}
+	source.accounts = map[string]*account{}
+	for i := 1; i <= n; i++ {
+		a := &account{ID: strconv.Itoa(i), Name: "name", Owner: "owner",
+			Password: "secret", Login: "login"}
+		source.list = append(source.list, a)
+		source.accounts[a.ID] = a
+	}
+	api.AddResourceWithRouter(router, &account{}, source)
+	return router
+}
+
+func TestSparseFieldsOfType(t *testing.T) {
+	router := newAccountAPI(1)
+
+	// password is a field of accounts, even though it is never marshalled
+	rec := serve(router, "GET", "/v1/accounts?fields[accounts]=name,password",
+		"")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(),
+		`"name"`) || strings.Contains(rec.Body.String(), "secret") {
+		t.Errorf("Expect the name only but got %d: %s", rec.Code, rec.Body)
+	}
+	rec = serve(router, "GET", "/v1/accounts?fields[accounts]=colour", "")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expect status %d but got %d: %s", http.StatusBadRequest,
+			rec.Code, rec.Body)
+	}
+}
+
+func benchmarkIndex(b *testing.B, target string) {
+	router := newAccountAPI(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if rec := serve(router, "GET", target, ""); rec.Code != http.StatusOK {
+			b.Fatalf("Expect status %d but got %d", http.StatusOK, rec.Code)
+		}
+	}
+}
+
+func BenchmarkIndex(b *testing.B) {
+	benchmarkIndex(b, "/v1/accounts")
+}
+
+func BenchmarkIndexSparseFields(b *testing.B) {
+	benchmarkIndex(b, "/v1/accounts?fields[accounts]=name,login")
+}
+
+func BenchmarkIndexTypeCache(b *testing.B) {
+	for _, cache := range []struct {
+		name string
+		on   bool
+	}{
+		{"cached", true},
+		{"tags", false},
+	} {
+		for _, n := range []int{10, 1000, 10000} {
+			b.Run(fmt.Sprint(cache.name, "/", n), func(b *testing.B) {
+				SetTypeCache(cache.on)
+				defer SetTypeCache(true)
+				router := newAccountAPI(n)
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					rec := serve(router, "GET", "/v1/accounts", "")
+					if rec.Code != http.StatusOK {
+						b.Fatalf("Expect status %d but got %d", http.StatusOK,
+							rec.Code)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkTypeInfo(b *testing.B) {
+	t := reflect.TypeOf(&account{})
+	for _, bench := range []struct {
+		name string
+		read func(reflect.Type) error
+	}{
+		{"tags", ReadTypeInfo},
+		{"cached", CachedTypeInfo},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := bench.read(t); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
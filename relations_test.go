@@ -0,0 +1,78 @@
+package api2go_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	. "github.com/cention-sany/api2go"
+)
+
+type relationCall struct {
+	method, id, name string
+	ids              []string
+}
+
+// relationSource edits relationships without FindOne and Update, which
+// would panic.
+type relationSource struct {
+	panickySource
+	calls []relationCall
+}
+
+func (s *relationSource) record(method, id, name string,
+	ids []string) (Responder, error) {
+	s.calls = append(s.calls, relationCall{method, id, name, ids})
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+func (s *relationSource) ReplaceRelationship(id, name string, ids []string,
+	req Request) (Responder, error) {
+	return s.record("replace", id, name, ids)
+}
+
+func (s *relationSource) AddToRelationship(id, name string, ids []string,
+	req Request) (Responder, error) {
+	return s.record("add", id, name, ids)
+}
+
+func (s *relationSource) RemoveFromRelationship(id, name string,
+	ids []string, req Request) (Responder, error) {
+	return s.record("remove", id, name, ids)
+}
+
+func TestRelationshipSource(t *testing.T) {
+	router := NewHTTPRouter("/v1")
+	api := NewAPI("v1", NewStaticResolver(""))
+	authors, books := &relationSource{}, &relationSource{}
+	api.AddResourceWithRouter(router, &author{}, authors)
+	api.AddResourceWithRouter(router, &book{}, books)
+
+	requests := []struct{ method, target, body string }{
+		{"PATCH", "/v1/authors/1/relationships/posts",
+			`{"data":[{"type":"posts","id":"1"},{"type":"posts","id":"2"}]}`},
+		{"POST", "/v1/authors/1/relationships/posts",
+			`{"data":[{"type":"posts","id":"3"}]}`},
+		{"DELETE", "/v1/authors/1/relationships/posts",
+			`{"data":[{"type":"posts","id":"1"}]}`},
+		{"PATCH", "/v1/books/2/relationships/author", `{"data":null}`},
+	}
+	for _, r := range requests {
+		if rec := serve(router, r.method, r.target, r.body); rec.Code != http.StatusNoContent {
+			t.Errorf("Expect status %d for %s %s but got %d: %s",
+				http.StatusNoContent, r.method, r.target, rec.Code, rec.Body)
+		}
+	}
+	exp := []relationCall{
+		{"replace", "1", "posts", []string{"1", "2"}},
+		{"add", "1", "posts", []string{"3"}},
+		{"remove", "1", "posts", []string{"1"}},
+	}
+	if !reflect.DeepEqual(exp, authors.calls) {
+		t.Errorf("Expect calls %v but got %v.", exp, authors.calls)
+	}
+	exp = []relationCall{{"replace", "2", "author", nil}}
+	if !reflect.DeepEqual(exp, books.calls) {
+		t.Errorf("Expect calls %v but got %v.", exp, books.calls)
+	}
+}
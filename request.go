@@ -2,10 +2,35 @@ package api2go
 
 import "net/http"
 
-// Request contains additional information for FindOne and Find Requests
+// Request contains additional information for FindOne and Find Requests.
+// The embedded APIContexter is a context.Context derived from the context of
+// the http request, so it carries deadlines, cancellation and the tracing
+// span of the data source call and can be passed on as is.
 type Request struct {
 	QueryParams map[string][]string
 	Pagination  map[string]string
+	// Fields is the sparse fieldset of a GET request by resource type,
+	// parsed from fields[type]=a,b. A type without entry is requested with
+	// all its fields. A data source may skip loading the fields which are
+	// not listed, they are removed from the response anyway. It is nil for
+	// the other methods, as the objects found for an update must be
+	// complete.
+	Fields map[string][]string
 	APIContexter
 	*http.Request
 }
+
+// FieldRequested tells if the attribute or relationship field of resource
+// type typ is part of the response, see Fields.
+func (r Request) FieldRequested(typ, field string) bool {
+	fields, ok := r.Fields[typ]
+	if !ok {
+		return true
+	}
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,225 @@
+package api2go
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Params holds the path parameters of a matched route, e.g. "id".
+type Params map[string]string
+
+// HandlerFunc serves a route registered on a Router.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request, params Params)
+
+// Router is the small routing abstraction the routes of a resource are
+// registered on, so that api2go can be mounted on any router. Paths are
+// relative to the router and use ":name" segments for parameters, e.g.
+// "/users/:id/relationships/sweets". GinRouter and HTTPRouter are the
+// bindings shipped with api2go.
+type Router interface {
+	Handle(method, path string, h HandlerFunc)
+}
+
+type ginRouter struct {
+	routes gin.IRoutes
+}
+
+// GinRouter binds a gin RouterGroup or Engine. The request scoped values of
+// the gin context are available through Request.Get.
+func GinRouter(routes gin.IRoutes) Router {
+	return ginRouter{routes: routes}
+}
+
+func (g ginRouter) Handle(method, path string, h HandlerFunc) {
+	g.routes.Handle(method, path, func(c *gin.Context) {
+		params := make(Params, len(c.Params))
+		for _, p := range c.Params {
+			params[p.Key] = p.Value
+		}
+		r := c.Request.WithContext(WithValueStore(c.Request.Context(), c))
+		h(c.Writer, r, params)
+	})
+}
+
+// HTTPRouter is a Router which is a plain http.Handler, for net/http and
+// routers like chi which can mount one:
+//
+//	router := api2go.NewHTTPRouter("/v1")
+//	api.AddResourceWithRouter(router, &User{}, users)
+//	http.ListenAndServe(":8080", router)
+//
+// A path without route is answered with 404 and a path without route for
+// the method with 405, both as JSON:API error documents.
+type HTTPRouter struct {
+	mu     sync.RWMutex
+	prefix []string
+	routes []httpRoute
+}
+
+type httpRoute struct {
+	method   string
+	segments []string
+	h        HandlerFunc
+}
+
+// NewHTTPRouter creates an HTTPRouter serving all routes below prefix,
+// which should match the prefix of the API.
+func NewHTTPRouter(prefix string) *HTTPRouter {
+	return &HTTPRouter{prefix: splitPath(prefix)}
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// Handle implements Router.
+func (r *HTTPRouter) Handle(method, path string, h HandlerFunc) {
+	segments := append(append([]string{}, r.prefix...), splitPath(path)...)
+	r.mu.Lock()
+	r.routes = append(r.routes, httpRoute{method: method, segments: segments,
+		h: h})
+	r.mu.Unlock()
+}
+
+// match returns the parameters if route matches the path segments.
+func (route httpRoute) match(segments []string) (Params, bool) {
+	if len(segments) != len(route.segments) {
+		return nil, false
+	}
+	params := Params{}
+	for i, s := range route.segments {
+		if strings.HasPrefix(s, ":") {
+			params[s[1:]] = segments[i]
+		} else if s != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// ServeHTTP implements http.Handler.
+func (r *HTTPRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	segments := splitPath(req.URL.Path)
+	var allowed []string
+	r.mu.RLock()
+	for _, route := range r.routes {
+		params, ok := route.match(segments)
+		if !ok {
+			continue
+		}
+		if route.method == req.Method {
+			r.mu.RUnlock()
+			route.h(w, req, params)
+			return
+		}
+		allowed = append(allowed, route.method)
+	}
+	r.mu.RUnlock()
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ","))
+		writeError(w, NewOnlyHTTPError(http.StatusMethodNotAllowed))
+		return
+	}
+	writeError(w, NewOnlyHTTPError(http.StatusNotFound))
+}
+
+// writeError writes e as JSON:API error document.
+func writeError(w http.ResponseWriter, e HTTPError) {
+	e.WriteContentType(w)
+	w.WriteHeader(e.status)
+	e.Render(w)
+}
+
+// responseWriter remembers the status code and body size for the
+// instrumentation and whether anything was written yet.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+// Status returns the written status code, 200 if none was written yet.
+func (w *responseWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Size returns the number of written body bytes.
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+// Written tells if the status code has been written.
+func (w *responseWriter) Written() bool {
+	return w.status != 0
+}
+
+// routeContext is what the handlers of a resource work on, independent of
+// the router binding.
+type routeContext struct {
+	Writer  *responseWriter
+	Request *http.Request
+	params  Params
+	store   ValueStore
+	loader  *includeLoader
+}
+
+func newRouteContext(w http.ResponseWriter, r *http.Request,
+	params Params) *routeContext {
+	return &routeContext{
+		Writer:  &responseWriter{ResponseWriter: w},
+		Request: r,
+		params:  params,
+		store:   valueStoreOf(r.Context()),
+	}
+}
+
+// Param returns the path parameter name.
+func (c *routeContext) Param(name string) string {
+	return c.params[name]
+}
+
+// Query returns the first value of the query parameter key.
+func (c *routeContext) Query(key string) string {
+	return c.Request.URL.Query().Get(key)
+}
+
+// Header sets a response header.
+func (c *routeContext) Header(key, value string) {
+	c.Writer.Header().Set(key, value)
+}
+
+// apiContext returns the APIContexter of the current request context.
+func (c *routeContext) apiContext() APIContexter {
+	return &apiContext{Context: c.Request.Context(), store: c.store}
+}
+
+// handler serves one route of a resource. The instrumentation, tracing and
+// recovery wrap it.
+type handler func(c *routeContext)
@@ -0,0 +1,85 @@
+package api2go_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/cention-sany/api2go"
+	"github.com/gin-gonic/gin"
+)
+
+func serve(h http.Handler, method, target,
+	body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, target, strings.NewReader(body))
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHTTPRouter(t *testing.T) {
+	router := NewHTTPRouter("/v1")
+	api := NewAPI("v1", NewStaticResolver(""))
+	api.AddResourceWithRouter(router, &post{}, newPostSource("first"))
+
+	rec := serve(router, "GET", "/v1/posts/1", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(),
+		`"first"`) {
+		t.Errorf("Expect post 1 but got %d: %s", rec.Code, rec.Body)
+	}
+	rec = serve(router, "POST", "/v1/posts",
+		`{"data":{"type":"posts","attributes":{"title":"second"}}}`)
+	if rec.Code != http.StatusCreated ||
+		!strings.HasSuffix(rec.Header().Get("Location"), "/posts/2") {
+		t.Errorf("Expect post 2 to be created but got %d: %s", rec.Code,
+			rec.Body)
+	}
+	if rec = serve(router, "DELETE", "/v1/posts/2", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("Expect status %d but got %d.", http.StatusNoContent, rec.Code)
+	}
+	if rec = serve(router, "GET", "/v1/posts/2", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expect status %d but got %d.", http.StatusNotFound, rec.Code)
+	}
+	if rec = serve(router, "GET", "/v1/users", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expect status %d for an unknown route but got %d.",
+			http.StatusNotFound, rec.Code)
+	}
+	rec = serve(router, "PUT", "/v1/posts/1", "")
+	if rec.Code != http.StatusMethodNotAllowed ||
+		rec.Header().Get("Allow") != "OPTIONS,GET,HEAD,DELETE,PATCH" {
+		t.Errorf("Expect status %d with Allow header but got %d %q.",
+			http.StatusMethodNotAllowed, rec.Code, rec.Header().Get("Allow"))
+	}
+}
+
+type contextSource struct {
+	*postSource
+	user interface{}
+	err  error
+}
+
+func (s *contextSource) FindOne(id string, req Request) (Responder, error) {
+	s.user, _ = req.Get("user")
+	s.err = req.Err()
+	return s.postSource.FindOne(id, req)
+}
+
+func TestRequestContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user", "marvin")
+	})
+	api := NewAPI("v1", NewStaticResolver(""))
+	source := &contextSource{postSource: newPostSource("first")}
+	api.AddResource(r.Group("/v1"), &post{}, source)
+
+	if rec := serve(r, "GET", "/v1/posts/1", ""); rec.Code != http.StatusOK {
+		t.Fatalf("Expect status %d but got %d.", http.StatusOK, rec.Code)
+	}
+	if source.user != "marvin" || source.err != nil {
+		t.Errorf("Expect the value set by the gin middleware and a live "+
+			"context but got %v %v.", source.user, source.err)
+	}
+}
@@ -0,0 +1,89 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/cention-sany/api2go"
+)
+
+// Group connects the Stores of related resources. api2go serves the linked
+// route /posts/1/comments by calling FindAll of the comments resource with
+// the query parameters postsID=1 and postsName=comments. A Store of a Group
+// answers such a request with the objects linked by post 1, read from the
+// join table or foreign key column of the Mapping of the posts Store. The
+// Stores of a Group must share one database.
+type Group struct {
+	mu     sync.RWMutex
+	stores map[string]*Store
+}
+
+// NewGroup creates an empty Group.
+func NewGroup() *Group {
+	return &Group{stores: map[string]*Store{}}
+}
+
+// Add creates a Store for prototype within the group, see New.
+func (g *Group) Add(db *sql.DB, prototype api2go.Identifier) (*Store,
+	error) {
+	s, err := New(db, prototype)
+	if err != nil {
+		return nil, err
+	}
+	return g.AddStore(s), nil
+}
+
+// AddStore adds an existing Store to the group.
+func (g *Group) AddStore(s *Store) *Store {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	s.group = g
+	g.stores[s.m.name] = s
+	return s
+}
+
+// linked returns the condition on the IDs of s linked by the parent of a
+// linked route, or "" if req is no such request.
+func (g *Group) linked(s *Store, req api2go.Request, a *args) (string,
+	error) {
+	if g == nil {
+		return "", nil
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	for name, parent := range g.stores {
+		ids := req.QueryParams[name+"ID"]
+		rels := req.QueryParams[name+"Name"]
+		if len(ids) == 0 || len(rels) == 0 {
+			continue
+		}
+		missing, err := parent.MissingIDs(ids[:1], req)
+		if err != nil {
+			return "", err
+		}
+		if len(missing) > 0 {
+			return "", notFound(name, ids[0])
+		}
+		pm := parent.m
+		for _, j := range pm.ToMany {
+			if j.Name == rels[0] {
+				return fmt.Sprintf("%s IN (SELECT %s FROM %s WHERE %s = %s)",
+					s.m.IDColumn, j.Target, j.Table, j.Owner,
+					a.add(ids[0])), nil
+			}
+		}
+		for _, c := range pm.ToOne {
+			if c.Name == rels[0] {
+				return fmt.Sprintf("%s IN (SELECT %s FROM %s WHERE %s = %s)",
+					s.m.IDColumn, c.Column, pm.Table, pm.IDColumn,
+					a.add(ids[0])), nil
+			}
+		}
+		return "", api2go.NewHTTPError(nil,
+			fmt.Sprintf("%s has no relationship %s", name, rels[0]),
+			http.StatusNotFound)
+	}
+	return "", nil
+}
@@ -0,0 +1,248 @@
+package sqlstore
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/cention-sany/api2go"
+)
+
+// Column maps a jsonapi attribute or to-one relationship to a table column.
+type Column struct {
+	// Name is the jsonapi attribute or relationship name.
+	Name string
+	// Column is the SQL column name.
+	Column string
+	index  int
+}
+
+// Join maps a to-many relationship to a join table with two columns, the
+// owner ID and the related ID.
+type Join struct {
+	// Name is the jsonapi relationship name.
+	Name string
+	// Table is the join table, e.g. posts_comments.
+	Table string
+	// Owner is the column holding the ID of the mapped object.
+	Owner string
+	// Target is the column holding the ID of the related object.
+	Target string
+	index  int
+}
+
+// Mapping describes how a jsonapi tagged struct is stored. It is derived by
+// NewMapping from the struct tags:
+//
+//	type Post struct {
+//		ID       string     `jsonapi:"primary,posts"`
+//		Title    string     `jsonapi:"attr,title"`
+//		Draft    bool       `jsonapi:"attr,draft" db:"-"`
+//		Author   *User      `jsonapi:"relation,author" db:"author_id"`
+//		Comments []*Comment `jsonapi:"relation,comments" db:"post_comments(post_id,comment_id)"`
+//	}
+//
+// The table is named after the resource and the ID column is "id". An
+// attribute is stored in the column of its db tag or, without one, in the
+// column named like the attribute; db:"-" leaves it out. A to-one
+// relationship is stored as foreign key column, by default <name>_id. A
+// to-many relationship is stored in a join table, by default
+// <table>_<name>(<table>_id,<name>_id).
+//
+// The fields may be changed before the Mapping is given to NewWithMapping.
+type Mapping struct {
+	Table    string
+	IDColumn string
+	Columns  []Column
+	ToOne    []Column
+	ToMany   []Join
+	name     string
+	typ      reflect.Type
+	isPtr    bool
+}
+
+// NewMapping derives the Mapping of prototype, which must be a struct or a
+// pointer to a struct with a jsonapi primary tag.
+func NewMapping(prototype api2go.Identifier) (*Mapping, error) {
+	t := reflect.TypeOf(prototype)
+	m := &Mapping{IDColumn: "id", isPtr: t.Kind() == reflect.Ptr}
+	if m.isPtr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("sqlstore: %T is no struct", prototype)
+	}
+	m.typ = t
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		args := strings.Split(f.Tag.Get("jsonapi"), ",")
+		if len(args) < 2 {
+			continue
+		}
+		name := args[1]
+		db, hasDB := f.Tag.Lookup("db")
+		if db == "-" {
+			continue
+		}
+		switch args[0] {
+		case "primary":
+			m.Table, m.name = name, name
+		case "attr":
+			if !hasDB {
+				db = name
+			}
+			m.Columns = append(m.Columns, Column{Name: name, Column: db,
+				index: i})
+		case "relation":
+			if f.Type.Kind() == reflect.Slice {
+				m.ToMany = append(m.ToMany, Join{Name: name, index: i})
+				if err := m.ToMany[len(m.ToMany)-1].parse(db); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if !hasDB {
+				db = name + "_id"
+			}
+			m.ToOne = append(m.ToOne, Column{Name: name, Column: db, index: i})
+		}
+	}
+	if m.Table == "" {
+		return nil, fmt.Errorf("sqlstore: %s has no jsonapi primary tag", t)
+	}
+	for i := range m.ToMany {
+		j := &m.ToMany[i]
+		if j.Table == "" {
+			j.Table = m.Table + "_" + j.Name
+		}
+		if j.Owner == "" {
+			j.Owner, j.Target = m.Table+"_id", j.Name+"_id"
+		}
+	}
+	return m, nil
+}
+
+// parse reads a join tag of the form table or table(owner,target).
+func (j *Join) parse(tag string) error {
+	if tag == "" {
+		return nil
+	}
+	open := strings.IndexByte(tag, '(')
+	if open < 0 {
+		j.Table = tag
+		return nil
+	}
+	cols := strings.Split(strings.TrimSuffix(tag[open+1:], ")"), ",")
+	if len(cols) != 2 || !strings.HasSuffix(tag, ")") {
+		return fmt.Errorf("sqlstore: invalid join tag %q of relationship %s",
+			tag, j.Name)
+	}
+	j.Table = tag[:open]
+	j.Owner = strings.TrimSpace(cols[0])
+	j.Target = strings.TrimSpace(cols[1])
+	return nil
+}
+
+// column returns the column of attribute name, "id" is the ID column.
+func (m *Mapping) column(name string) (string, bool) {
+	if name == "id" {
+		return m.IDColumn, true
+	}
+	for _, c := range m.Columns {
+		if c.Name == name {
+			return c.Column, true
+		}
+	}
+	return "", false
+}
+
+// selection is the part of a Mapping which is loaded for a request.
+type selection struct {
+	idColumn string
+	columns  []Column
+	toOne    []Column
+	toMany   []Join
+}
+
+// all selects every mapped field.
+func (m *Mapping) all() selection {
+	return selection{m.IDColumn, m.Columns, m.ToOne, m.ToMany}
+}
+
+// selection selects the fields of the sparse fieldset of req, so that
+// neither unrequested columns nor join tables are read.
+func (m *Mapping) selection(req api2go.Request) selection {
+	if _, ok := req.Fields[m.name]; !ok {
+		return m.all()
+	}
+	sel := selection{idColumn: m.IDColumn}
+	for _, c := range m.Columns {
+		if req.FieldRequested(m.name, c.Name) {
+			sel.columns = append(sel.columns, c)
+		}
+	}
+	for _, c := range m.ToOne {
+		if req.FieldRequested(m.name, c.Name) {
+			sel.toOne = append(sel.toOne, c)
+		}
+	}
+	for _, j := range m.ToMany {
+		if req.FieldRequested(m.name, j.Name) {
+			sel.toMany = append(sel.toMany, j)
+		}
+	}
+	return sel
+}
+
+// names lists the ID, attribute and to-one columns in scan order.
+func (sel selection) names() []string {
+	cols := []string{sel.idColumn}
+	for _, c := range sel.columns {
+		cols = append(cols, c.Column)
+	}
+	for _, c := range sel.toOne {
+		cols = append(cols, c.Column)
+	}
+	return cols
+}
+
+// selectColumns lists all ID, attribute and to-one columns in scan order.
+func (m *Mapping) selectColumns() []string {
+	return m.all().names()
+}
+
+// related returns a new related object for field f with the given ID, in
+// the form of the field element type.
+func related(t reflect.Type, id string) (reflect.Value, error) {
+	isPtr := t.Kind() == reflect.Ptr
+	if isPtr {
+		t = t.Elem()
+	}
+	p := reflect.New(t)
+	s, ok := p.Interface().(api2go.UnmarshalIdentifier)
+	if !ok {
+		return p, fmt.Errorf("sqlstore: %s must implement SetID", t)
+	}
+	if err := s.SetID(id); err != nil {
+		return p, err
+	}
+	if isPtr {
+		return p, nil
+	}
+	return p.Elem(), nil
+}
+
+// relatedID returns the ID of the related object v or "" if there is none.
+func relatedID(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+	} else if v.CanAddr() {
+		v = v.Addr()
+	}
+	if o, ok := v.Interface().(api2go.Identifier); ok {
+		return o.GetID()
+	}
+	return ""
+}
@@ -0,0 +1,194 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cention-sany/api2go"
+)
+
+// ReplaceRelationship implements api2go.RelationshipReplacer. A to-one
+// relationship is set with one UPDATE of its column, a to-many relationship
+// by rewriting the rows of the object in its join table.
+func (s *Store) ReplaceRelationship(id, name string, ids []string,
+	req api2go.Request) (api2go.Responder, error) {
+	ctx := contextOf(req)
+	for _, c := range s.m.ToOne {
+		if c.Name != name {
+			continue
+		}
+		if len(ids) > 1 {
+			return nil, api2go.NewHTTPError(nil,
+				"A to-one relationship takes one linkage",
+				http.StatusBadRequest)
+		}
+		var target interface{}
+		if len(ids) == 1 {
+			target = ids[0]
+		}
+		err := s.inTx(ctx, func(tx *sql.Tx) error {
+			if err := s.exists(ctx, tx, id); err != nil {
+				return err
+			}
+			a := &args{s: s}
+			q := fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s = %s",
+				s.m.Table, c.Column, a.add(target), s.m.IDColumn, a.add(id))
+			_, err := tx.ExecContext(ctx, q, a.vals...)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &api2go.Response{Code: http.StatusNoContent}, nil
+	}
+	return s.editJoin(ctx, id, name, func(tx *sql.Tx, j Join) error {
+		a := &args{s: s}
+		q := fmt.Sprintf("DELETE FROM %s WHERE %s = %s", j.Table, j.Owner,
+			a.add(id))
+		if _, err := tx.ExecContext(ctx, q, a.vals...); err != nil {
+			return err
+		}
+		return s.insertJoin(ctx, tx, j, id, ids, nil)
+	})
+}
+
+// AddToRelationship implements api2go.RelationshipEditor with INSERTs of the
+// IDs which are not yet in the join table.
+func (s *Store) AddToRelationship(id, name string, ids []string,
+	req api2go.Request) (api2go.Responder, error) {
+	ctx := contextOf(req)
+	return s.editJoin(ctx, id, name, func(tx *sql.Tx, j Join) error {
+		a := &args{s: s}
+		q := fmt.Sprintf("SELECT %s FROM %s WHERE %s = %s", j.Target,
+			j.Table, j.Owner, a.add(id))
+		rows, err := tx.QueryContext(ctx, q, a.vals...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		linked := map[string]bool{}
+		for rows.Next() {
+			var target string
+			if err := rows.Scan(&target); err != nil {
+				return err
+			}
+			linked[target] = true
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		rows.Close()
+		return s.insertJoin(ctx, tx, j, id, ids, linked)
+	})
+}
+
+// RemoveFromRelationship implements api2go.RelationshipEditor with one
+// DELETE on the join table.
+func (s *Store) RemoveFromRelationship(id, name string, ids []string,
+	req api2go.Request) (api2go.Responder, error) {
+	ctx := contextOf(req)
+	return s.editJoin(ctx, id, name, func(tx *sql.Tx, j Join) error {
+		if len(ids) == 0 {
+			return nil
+		}
+		a := &args{s: s}
+		owner := a.add(id)
+		in := make([]string, len(ids))
+		for i, target := range ids {
+			in[i] = a.add(target)
+		}
+		q := fmt.Sprintf("DELETE FROM %s WHERE %s = %s AND %s IN (%s)",
+			j.Table, j.Owner, owner, j.Target, strings.Join(in, ", "))
+		_, err := tx.ExecContext(ctx, q, a.vals...)
+		return err
+	})
+}
+
+// MissingIDs implements api2go.ExistenceChecker with one SELECT.
+func (s *Store) MissingIDs(ids []string, req api2go.Request) ([]string,
+	error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	a := &args{s: s}
+	in := make([]string, len(ids))
+	for i, id := range ids {
+		in[i] = a.add(id)
+	}
+	q := fmt.Sprintf("SELECT %s FROM %s WHERE %s IN (%s)", s.m.IDColumn,
+		s.m.Table, s.m.IDColumn, strings.Join(in, ", "))
+	rows, err := s.db.QueryContext(contextOf(req), q, a.vals...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	found := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		found[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+// editJoin runs edit on the join table of the to-many relationship name in a
+// transaction, after checking that the object with id exists.
+func (s *Store) editJoin(ctx context.Context, id, name string,
+	edit func(tx *sql.Tx, j Join) error) (api2go.Responder, error) {
+	var join *Join
+	for i := range s.m.ToMany {
+		if s.m.ToMany[i].Name == name {
+			join = &s.m.ToMany[i]
+		}
+	}
+	if join == nil {
+		return nil, api2go.NewHTTPError(nil,
+			fmt.Sprintf("%s has no relationship %s", s.m.Table, name),
+			http.StatusNotFound)
+	}
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := s.exists(ctx, tx, id); err != nil {
+			return err
+		}
+		return edit(tx, *join)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api2go.Response{Code: http.StatusNoContent}, nil
+}
+
+// insertJoin inserts a row for every target of ids which is not linked yet.
+func (s *Store) insertJoin(ctx context.Context, tx *sql.Tx, j Join, id string,
+	ids []string, linked map[string]bool) error {
+	if linked == nil {
+		linked = map[string]bool{}
+	}
+	for _, target := range ids {
+		if target == "" || linked[target] {
+			continue
+		}
+		linked[target] = true
+		a := &args{s: s}
+		q := fmt.Sprintf("INSERT INTO %s (%s, %s) VALUES (%s, %s)", j.Table,
+			j.Owner, j.Target, a.add(id), a.add(target))
+		if _, err := tx.ExecContext(ctx, q, a.vals...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
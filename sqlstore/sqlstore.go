@@ -0,0 +1,660 @@
+// Package sqlstore provides a generic api2go data source on top of
+// database/sql. It maps jsonapi tagged structs to tables, see Mapping:
+//
+//	posts, err := sqlstore.New(db, &model.Post{})
+//	api.AddResource(rg, &model.Post{}, posts)
+//
+// The tables must exist already. Store implements api2go.CRUD, FindAll,
+// FindMany and PaginatedFindAll with sort=a,-b and filter[attr]=x,y
+// parameters. To-many relationships are kept in join tables which are
+// written in the same transaction as the object. The relationship routes
+// change the foreign key column or the join table directly, as Store
+// implements api2go.RelationshipReplacer and api2go.RelationshipEditor, and
+// it checks linkage for API.ReferentialIntegrity as api2go.ExistenceChecker.
+// Only the columns and join tables of the sparse fieldset in
+// api2go.Request.Fields are read. Stores created by one Group answer the
+// linked resource routes such as /posts/1/comments, see Group.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cention-sany/api2go"
+)
+
+// Question renders the n-th placeholder as ?, like SQLite and MySQL do.
+func Question(n int) string {
+	return "?"
+}
+
+// Dollar renders the n-th placeholder as $n, like PostgreSQL does. Set
+// Store.Returning or Store.NewID for PostgreSQL as well.
+func Dollar(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// Store is a data source for one resource type stored in one table.
+type Store struct {
+	// Placeholder renders the n-th query argument, starting at 1. It is
+	// Question by default.
+	Placeholder func(n int) string
+	// NewID returns the ID of a created object without one. If it is nil
+	// the ID assigned by the database is taken from sql.Result.LastInsertId,
+	// or with Returning from INSERT ... RETURNING.
+	NewID func() string
+	// Returning reads the ID assigned by the database with INSERT ...
+	// RETURNING. PostgreSQL needs it, or NewID, as lib/pq and pgx do not
+	// support sql.Result.LastInsertId.
+	Returning bool
+
+	db    *sql.DB
+	m     *Mapping
+	group *Group
+}
+
+// New creates a Store for prototype with the Mapping derived by NewMapping.
+func New(db *sql.DB, prototype api2go.Identifier) (*Store, error) {
+	m, err := NewMapping(prototype)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithMapping(db, m), nil
+}
+
+// NewWithMapping creates a Store which stores objects as described by m.
+func NewWithMapping(db *sql.DB, m *Mapping) *Store {
+	return &Store{Placeholder: Question, db: db, m: m}
+}
+
+// Mapping returns the mapping of the store.
+func (s *Store) Mapping() *Mapping {
+	return s.m
+}
+
+func notFound(name, id string) error {
+	msg := fmt.Sprintf("%s with id %s not found", name, id)
+	return api2go.NewHTTPError(nil, msg, http.StatusNotFound)
+}
+
+func contextOf(req api2go.Request) context.Context {
+	if req.Request != nil {
+		return req.Context()
+	}
+	return context.Background()
+}
+
+// args collects query arguments and renders their placeholders.
+type args struct {
+	s    *Store
+	vals []interface{}
+}
+
+func (a *args) add(v interface{}) string {
+	a.vals = append(a.vals, v)
+	return a.s.Placeholder(len(a.vals))
+}
+
+// structOf returns the addressable struct of obj, which must be of the
+// mapped type.
+func (s *Store) structOf(obj interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(obj)
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	} else if v.Kind() == reflect.Struct {
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		v = p.Elem()
+	}
+	if v.Kind() != reflect.Struct || v.Type() != s.m.typ {
+		return v, api2go.NewHTTPError(fmt.Errorf("sqlstore: got %T", obj),
+			"Invalid instance given", http.StatusBadRequest)
+	}
+	return v, nil
+}
+
+// out returns the struct v in the form of the prototype.
+func (s *Store) out(v reflect.Value) interface{} {
+	if s.m.isPtr {
+		return v.Addr().Interface()
+	}
+	return v.Interface()
+}
+
+// values returns the column values of the attributes and to-one
+// relationships of v in the order of selectColumns without the ID.
+func (s *Store) values(v reflect.Value) []interface{} {
+	var vals []interface{}
+	for _, c := range s.m.Columns {
+		vals = append(vals, v.Field(c.index).Interface())
+	}
+	for _, c := range s.m.ToOne {
+		if id := relatedID(v.Field(c.index)); id != "" {
+			vals = append(vals, id)
+		} else {
+			vals = append(vals, nil)
+		}
+	}
+	return vals
+}
+
+// scan reads a row of the columns of sel into a new struct.
+func (s *Store) scan(rows *sql.Rows, sel selection) (reflect.Value, error) {
+	v := reflect.New(s.m.typ).Elem()
+	var id string
+	dest := []interface{}{&id}
+	for _, c := range sel.columns {
+		dest = append(dest, v.Field(c.index).Addr().Interface())
+	}
+	toOne := make([]sql.NullString, len(sel.toOne))
+	for i := range toOne {
+		dest = append(dest, &toOne[i])
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return v, err
+	}
+	if err := v.Addr().Interface().(api2go.UnmarshalIdentifier).
+		SetID(id); err != nil {
+		return v, err
+	}
+	for i, c := range sel.toOne {
+		if !toOne[i].Valid {
+			continue
+		}
+		f := v.Field(c.index)
+		r, err := related(f.Type(), toOne[i].String)
+		if err != nil {
+			return v, err
+		}
+		f.Set(r)
+	}
+	return v, nil
+}
+
+// loadToMany fills the to-many relationships of objs from the join tables.
+func (s *Store) loadToMany(ctx context.Context, objs []reflect.Value,
+	joins []Join) error {
+	if len(objs) == 0 {
+		return nil
+	}
+	byID := make(map[string]reflect.Value, len(objs))
+	for _, v := range objs {
+		byID[relatedID(v)] = v
+	}
+	for _, j := range joins {
+		a := &args{s: s}
+		ph := make([]string, 0, len(objs))
+		for id := range byID {
+			ph = append(ph, a.add(id))
+		}
+		q := fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s IN (%s) ORDER BY %s",
+			j.Owner, j.Target, j.Table, j.Owner, strings.Join(ph, ", "),
+			j.Target)
+		rows, err := s.db.QueryContext(ctx, q, a.vals...)
+		if err != nil {
+			return err
+		}
+		for _, v := range objs {
+			f := v.Field(j.index)
+			f.Set(reflect.MakeSlice(f.Type(), 0, 0))
+		}
+		for rows.Next() {
+			var owner, target string
+			if err := rows.Scan(&owner, &target); err != nil {
+				rows.Close()
+				return err
+			}
+			f := byID[owner].Field(j.index)
+			r, err := related(f.Type().Elem(), target)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			f.Set(reflect.Append(f, r))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storeToMany replaces the join table rows of v within tx.
+func (s *Store) storeToMany(ctx context.Context, tx *sql.Tx, id string,
+	v reflect.Value) error {
+	for _, j := range s.m.ToMany {
+		a := &args{s: s}
+		q := fmt.Sprintf("DELETE FROM %s WHERE %s = %s", j.Table, j.Owner,
+			a.add(id))
+		if _, err := tx.ExecContext(ctx, q, a.vals...); err != nil {
+			return err
+		}
+		f := v.Field(j.index)
+		seen := map[string]bool{}
+		for i := 0; i < f.Len(); i++ {
+			target := relatedID(f.Index(i))
+			if target == "" || seen[target] {
+				continue
+			}
+			seen[target] = true
+			a := &args{s: s}
+			q := fmt.Sprintf("INSERT INTO %s (%s, %s) VALUES (%s, %s)",
+				j.Table, j.Owner, j.Target, a.add(id), a.add(target))
+			if _, err := tx.ExecContext(ctx, q, a.vals...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// inTx runs f in a transaction which is committed if f succeeds.
+func (s *Store) inTx(ctx context.Context, f func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// exists returns an HTTPError with 404 if the object with id does not exist.
+// The affected rows of an UPDATE can not tell, as MySQL does not count rows
+// which are left unchanged.
+func (s *Store) exists(ctx context.Context, tx *sql.Tx, id string) error {
+	found, err := s.has(ctx, tx, id)
+	if err == nil && !found {
+		return notFound(s.m.Table, id)
+	}
+	return err
+}
+
+// has tells if the row with id exists.
+func (s *Store) has(ctx context.Context, tx *sql.Tx, id string) (bool,
+	error) {
+	a := &args{s: s}
+	q := fmt.Sprintf("SELECT %s FROM %s WHERE %s = %s", s.m.IDColumn,
+		s.m.Table, s.m.IDColumn, a.add(id))
+	var found string
+	err := tx.QueryRowContext(ctx, q, a.vals...).Scan(&found)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// find returns one object or an HTTPError with 404.
+func (s *Store) find(ctx context.Context, id string, sel selection) (
+	reflect.Value, error) {
+	a := &args{s: s}
+	q := fmt.Sprintf("SELECT %s FROM %s WHERE %s = %s",
+		strings.Join(sel.names(), ", "), s.m.Table, s.m.IDColumn, a.add(id))
+	objs, err := s.query(ctx, q, a.vals, sel)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if len(objs) == 0 {
+		return reflect.Value{}, notFound(s.m.Table, id)
+	}
+	return objs[0], nil
+}
+
+// query runs a select of the columns of sel and loads the selected to-many
+// relationships.
+func (s *Store) query(ctx context.Context, q string, vals []interface{},
+	sel selection) ([]reflect.Value, error) {
+	rows, err := s.db.QueryContext(ctx, q, vals...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var objs []reflect.Value
+	for rows.Next() {
+		v, err := s.scan(rows, sel)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	return objs, s.loadToMany(ctx, objs, sel.toMany)
+}
+
+// FindOne implements api2go.CRUD.
+func (s *Store) FindOne(id string, req api2go.Request) (api2go.Responder,
+	error) {
+	v, err := s.find(contextOf(req), id, s.m.selection(req))
+	if err != nil {
+		return nil, err
+	}
+	return &api2go.Response{Res: s.out(v), Code: http.StatusOK}, nil
+}
+
+// Create implements api2go.CRUD. A client generated ID is kept, an ID which
+// is taken gives an HTTPError with 409.
+func (s *Store) Create(obj interface{}, req api2go.Request) (api2go.Responder,
+	error) {
+	v, err := s.structOf(obj)
+	if err != nil {
+		return nil, err
+	}
+	ctx := contextOf(req)
+	id := relatedID(v)
+	if id == "" && s.NewID != nil {
+		id = s.NewID()
+	}
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		a := &args{s: s}
+		cols := s.m.selectColumns()
+		var ph []string
+		if id == "" {
+			cols = cols[1:]
+		} else {
+			found, err := s.has(ctx, tx, id)
+			if err != nil {
+				return err
+			}
+			if found {
+				return api2go.NewHTTPError(nil,
+					fmt.Sprintf("%s with id %s already exists", s.m.Table, id),
+					http.StatusConflict)
+			}
+			ph = append(ph, a.add(id))
+		}
+		for _, val := range s.values(v) {
+			ph = append(ph, a.add(val))
+		}
+		q := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", s.m.Table,
+			strings.Join(cols, ", "), strings.Join(ph, ", "))
+		if id == "" && s.Returning {
+			q += " RETURNING " + s.m.IDColumn
+			if err := tx.QueryRowContext(ctx, q, a.vals...).
+				Scan(&id); err != nil {
+				return err
+			}
+			return s.storeToMany(ctx, tx, id, v)
+		}
+		res, err := tx.ExecContext(ctx, q, a.vals...)
+		if err != nil {
+			return err
+		}
+		if id == "" {
+			n, err := res.LastInsertId()
+			if err != nil {
+				return err
+			}
+			id = strconv.FormatInt(n, 10)
+		}
+		return s.storeToMany(ctx, tx, id, v)
+	})
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.find(ctx, id, s.m.all())
+	if err != nil {
+		return nil, err
+	}
+	return &api2go.Response{Res: s.out(created), Code: http.StatusCreated},
+		nil
+}
+
+// Update implements api2go.CRUD. The row and the join tables are written in
+// one transaction.
+func (s *Store) Update(obj interface{}, req api2go.Request) (api2go.Responder,
+	error) {
+	v, err := s.structOf(obj)
+	if err != nil {
+		return nil, err
+	}
+	ctx := contextOf(req)
+	id := relatedID(v)
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := s.exists(ctx, tx, id); err != nil {
+			return err
+		}
+		a := &args{s: s}
+		var set []string
+		vals := s.values(v)
+		for i, col := range s.m.selectColumns()[1:] {
+			set = append(set, col+" = "+a.add(vals[i]))
+		}
+		if len(set) > 0 {
+			q := fmt.Sprintf("UPDATE %s SET %s WHERE %s = %s", s.m.Table,
+				strings.Join(set, ", "), s.m.IDColumn, a.add(id))
+			if _, err := tx.ExecContext(ctx, q, a.vals...); err != nil {
+				return err
+			}
+		}
+		return s.storeToMany(ctx, tx, id, v)
+	})
+	if err != nil {
+		return nil, err
+	}
+	updated, err := s.find(ctx, id, s.m.all())
+	if err != nil {
+		return nil, err
+	}
+	return &api2go.Response{Res: s.out(updated), Code: http.StatusOK}, nil
+}
+
+// Delete implements api2go.CRUD and removes the join table rows of the
+// object as well.
+func (s *Store) Delete(id string, req api2go.Request) (api2go.Responder,
+	error) {
+	ctx := contextOf(req)
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, j := range s.m.ToMany {
+			a := &args{s: s}
+			q := fmt.Sprintf("DELETE FROM %s WHERE %s = %s", j.Table, j.Owner,
+				a.add(id))
+			if _, err := tx.ExecContext(ctx, q, a.vals...); err != nil {
+				return err
+			}
+		}
+		a := &args{s: s}
+		q := fmt.Sprintf("DELETE FROM %s WHERE %s = %s", s.m.Table,
+			s.m.IDColumn, a.add(id))
+		res, err := tx.ExecContext(ctx, q, a.vals...)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return notFound(s.m.Table, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api2go.Response{Code: http.StatusNoContent}, nil
+}
+
+// FindAll implements api2go.FindAll.
+func (s *Store) FindAll(req api2go.Request) (api2go.Responder, error) {
+	a := &args{s: s}
+	where, order, err := s.clauses(req, a)
+	if err != nil {
+		return nil, err
+	}
+	sel := s.m.selection(req)
+	objs, err := s.query(contextOf(req), s.selectFrom(sel)+where+order,
+		a.vals, sel)
+	if err != nil {
+		return nil, err
+	}
+	return &api2go.Response{Res: s.outSlice(objs), Code: http.StatusOK}, nil
+}
+
+// FindMany implements api2go.FindMany with one SELECT and one per selected
+// join table. The objects are returned in the order of ids.
+func (s *Store) FindMany(ids []string, req api2go.Request) (api2go.Responder,
+	error) {
+	sel := s.m.selection(req)
+	if len(ids) == 0 {
+		return &api2go.Response{Res: s.outSlice(nil), Code: http.StatusOK},
+			nil
+	}
+	a := &args{s: s}
+	in := make([]string, len(ids))
+	for i, id := range ids {
+		in[i] = a.add(id)
+	}
+	q := fmt.Sprintf("%s WHERE %s IN (%s)", s.selectFrom(sel), s.m.IDColumn,
+		strings.Join(in, ", "))
+	objs, err := s.query(contextOf(req), q, a.vals, sel)
+	if err != nil {
+		return nil, err
+	}
+	byID := map[string]reflect.Value{}
+	for _, o := range objs {
+		byID[relatedID(o)] = o
+	}
+	sorted := make([]reflect.Value, 0, len(objs))
+	for _, id := range ids {
+		if o, ok := byID[id]; ok {
+			sorted = append(sorted, o)
+			delete(byID, id)
+		}
+	}
+	return &api2go.Response{Res: s.outSlice(sorted), Code: http.StatusOK},
+		nil
+}
+
+// PaginatedFindAll implements api2go.PaginatedFindAll with LIMIT and OFFSET
+// from api2go.OffsetPage.
+func (s *Store) PaginatedFindAll(req api2go.Request) (uint, api2go.Responder,
+	error) {
+	ok, offset, limit, err := api2go.OffsetPage(&req)
+	if err != nil {
+		return 0, nil, api2go.NewHTTPError(err, err.Error(),
+			http.StatusBadRequest)
+	}
+	a := &args{s: s}
+	where, order, err := s.clauses(req, a)
+	if err != nil {
+		return 0, nil, err
+	}
+	ctx := contextOf(req)
+	var total uint
+	q := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", s.m.Table, where)
+	if err := s.db.QueryRowContext(ctx, q, a.vals...).Scan(&total); err != nil {
+		return 0, nil, err
+	}
+	page := ""
+	if ok {
+		if limit < 0 {
+			limit = math.MaxInt64
+		}
+		page = fmt.Sprintf(" LIMIT %s OFFSET %s", a.add(limit), a.add(offset))
+	}
+	sel := s.m.selection(req)
+	objs, err := s.query(ctx, s.selectFrom(sel)+where+order+page, a.vals, sel)
+	if err != nil {
+		return 0, nil, err
+	}
+	return total, &api2go.Response{Res: s.outSlice(objs),
+		Code: http.StatusOK}, nil
+}
+
+func (s *Store) selectFrom(sel selection) string {
+	return fmt.Sprintf("SELECT %s FROM %s", strings.Join(sel.names(), ", "),
+		s.m.Table)
+}
+
+// clauses builds the WHERE clause of a linked route and of the
+// filter[attr]=a,b parameters and the ORDER BY clause of the sort
+// parameter. Only mapped attributes are accepted, the values are passed as
+// arguments.
+func (s *Store) clauses(req api2go.Request, a *args) (string, string,
+	error) {
+	var conds []string
+	cond, err := s.group.linked(s, req, a)
+	if err != nil {
+		return "", "", err
+	}
+	if cond != "" {
+		conds = append(conds, cond)
+	}
+	for _, k := range sortedKeys(req.QueryParams) {
+		if !strings.HasPrefix(k, "filter[") || !strings.HasSuffix(k, "]") {
+			continue
+		}
+		name := k[len("filter[") : len(k)-1]
+		col, ok := s.m.column(name)
+		if !ok {
+			return "", "", api2go.NewHTTPError(nil,
+				fmt.Sprintf("Can not filter %s by unknown attribute %s",
+					s.m.Table, name), http.StatusBadRequest)
+		}
+		var ph []string
+		for _, val := range req.QueryParams[k] {
+			for _, val := range strings.Split(val, ",") {
+				ph = append(ph, a.add(val))
+			}
+		}
+		conds = append(conds, fmt.Sprintf("%s IN (%s)", col,
+			strings.Join(ph, ", ")))
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+	var keys []string
+	for _, f := range req.QueryParams["sort"] {
+		for _, f := range strings.Split(f, ",") {
+			if f == "" {
+				continue
+			}
+			name := strings.TrimPrefix(f, "-")
+			col, ok := s.m.column(name)
+			if !ok {
+				return "", "", api2go.NewHTTPError(nil,
+					fmt.Sprintf("Can not sort %s by unknown attribute %s",
+						s.m.Table, name), http.StatusBadRequest)
+			}
+			if strings.HasPrefix(f, "-") {
+				col += " DESC"
+			}
+			keys = append(keys, col)
+		}
+	}
+	// a stable order is needed for pagination
+	keys = append(keys, s.m.IDColumn)
+	return where, " ORDER BY " + strings.Join(keys, ", "), nil
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// outSlice returns objs as slice of the prototype type.
+func (s *Store) outSlice(objs []reflect.Value) interface{} {
+	t := s.m.typ
+	if s.m.isPtr {
+		t = reflect.PtrTo(t)
+	}
+	res := reflect.MakeSlice(reflect.SliceOf(t), 0, len(objs))
+	for _, v := range objs {
+		res = reflect.Append(res, reflect.ValueOf(s.out(v)))
+	}
+	return res.Interface()
+}
@@ -0,0 +1,413 @@
+package sqlstore_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/cention-sany/api2go"
+	"github.com/cention-sany/api2go/api2gotest"
+	"github.com/cention-sany/api2go/sqlstore"
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+CREATE TABLE posts (
+	id INTEGER PRIMARY KEY,
+	title TEXT NOT NULL,
+	views INTEGER NOT NULL,
+	author_id INTEGER REFERENCES users(id)
+);
+CREATE TABLE comments (id TEXT PRIMARY KEY);
+CREATE TABLE post_comments (post_id INTEGER, comment_id TEXT,
+	PRIMARY KEY (post_id, comment_id));
+`
+
+type user struct {
+	ID   string `jsonapi:"primary,users"`
+	Name string `jsonapi:"attr,name"`
+}
+
+func (u user) GetID() string { return u.ID }
+
+func (u *user) SetID(id string) error {
+	u.ID = id
+	return nil
+}
+
+type comment struct {
+	ID string `jsonapi:"primary,comments"`
+}
+
+func (c comment) GetID() string { return c.ID }
+
+func (c *comment) SetID(id string) error {
+	c.ID = id
+	return nil
+}
+
+type post struct {
+	ID       string     `jsonapi:"primary,posts"`
+	Title    string     `jsonapi:"attr,title"`
+	Views    int        `jsonapi:"attr,views"`
+	Draft    bool       `jsonapi:"attr,draft" db:"-"`
+	Author   *user      `jsonapi:"relation,author"`
+	Comments []*comment `jsonapi:"relation,comments" db:"post_comments(post_id,comment_id)"`
+}
+
+func (p post) GetID() string { return p.ID }
+
+func (p *post) SetID(id string) error {
+	p.ID = id
+	return nil
+}
+
+func (p *post) AddToManyIDs(name string, IDs []string) error {
+	if name != "comments" {
+		return errors.New("There is no to-many relationship " + name)
+	}
+	for _, id := range IDs {
+		p.Comments = append(p.Comments, &comment{ID: id})
+	}
+	return nil
+}
+
+func (p *post) DeleteToManyIDs(name string, IDs []string) error {
+	if name != "comments" {
+		return errors.New("There is no to-many relationship " + name)
+	}
+	for _, id := range IDs {
+		for i, c := range p.Comments {
+			if c.ID == id {
+				p.Comments = append(p.Comments[:i], p.Comments[i+1:]...)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func open(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection has its own in-memory database
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO users (id, name) VALUES (7, 'Lem')`); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newPosts(t *testing.T) *sqlstore.Store {
+	s, err := sqlstore.New(open(t), &post{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestMapping(t *testing.T) {
+	m, err := sqlstore.NewMapping(&post{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Table != "posts" || len(m.Columns) != 2 {
+		t.Errorf("Expect table posts with 2 columns but got %s %v.", m.Table,
+			m.Columns)
+	}
+	if len(m.ToOne) != 1 || m.ToOne[0].Column != "author_id" {
+		t.Errorf("Expect to-one column author_id but got %v.", m.ToOne)
+	}
+	j := m.ToMany[0]
+	if j.Table != "post_comments" || j.Owner != "post_id" ||
+		j.Target != "comment_id" {
+		t.Errorf("Expect join post_comments(post_id,comment_id) but got %v.", j)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	api2gotest.Conformance{
+		Prototype: &post{},
+		Source:    newPosts(t),
+		New: func(i int) api2go.Identifier {
+			return &post{
+				Title:    fmt.Sprint("Post ", i),
+				Views:    i,
+				Author:   &user{ID: "7"},
+				Comments: []*comment{{ID: "a"}, {ID: "b"}},
+			}
+		},
+		Modify: func(obj api2go.Identifier) api2go.Identifier {
+			p := *obj.(*post)
+			p.Views += 10
+			p.Author = nil
+			return &p
+		},
+	}.Run(t)
+}
+
+func TestQuery(t *testing.T) {
+	posts := newPosts(t)
+	for i, title := range []string{"b", "d", "a", "c"} {
+		p := &post{Title: title, Views: i % 2}
+		if _, err := posts.Create(p, api2go.Request{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv := api2gotest.NewServer("v1").AddResource(&post{}, posts)
+
+	srv.GET(t, "/v1/posts").Query("sort", "-views,title").Do().
+		Status(http.StatusOK).
+		DataIDs("4", "2", "3", "1")
+	srv.GET(t, "/v1/posts").Query("filter[title]", "a,d").Do().
+		DataIDs("2", "3")
+	srv.GET(t, "/v1/posts").Query("filter[draft]", "true").Do().
+		Status(http.StatusBadRequest)
+	srv.GET(t, "/v1/posts").Query("sort", "title; DROP TABLE posts").Do().
+		Status(http.StatusBadRequest)
+	srv.GET(t, "/v1/posts").Query("sort", "title").
+		Query("page[offset]", "1").Query("page[limit]", "2").Do().
+		DataIDs("1", "4").
+		Link("next", "http://localhost/v1/posts?page[limit]=2&page[offset]=3&sort=title")
+	srv.GET(t, "/v1/posts").Query("filter[id]", "4,9,2").Do().
+		Status(http.StatusOK).
+		DataIDs("4", "2")
+}
+
+func TestEditToMany(t *testing.T) {
+	posts := newPosts(t)
+	if _, err := posts.Create(&post{Title: "x"}, api2go.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	srv := api2gotest.NewServer("v1").AddResource(&post{}, posts)
+	srv.POST(t, "/v1/posts/1/relationships/comments").
+		Linkage("comments", "c1", "c2").
+		Do().Status(http.StatusNoContent)
+	srv.DELETE(t, "/v1/posts/1/relationships/comments").
+		Linkage("comments", "c1").
+		Do().Status(http.StatusNoContent)
+	rsp, err := posts.FindOne("1", api2go.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := rsp.Result().(*post)
+	if len(p.Comments) != 1 || p.Comments[0].ID != "c2" {
+		t.Errorf("Expect comment c2 but got %v.", p.Comments)
+	}
+
+	srv.POST(t, "/v1/posts/1/relationships/comments").
+		Linkage("comments", "c2", "c3").
+		Do().Status(http.StatusNoContent)
+	srv.PATCH(t, "/v1/posts/1/relationships/author").
+		JSON(map[string]interface{}{
+			"data": map[string]string{"type": "users", "id": "7"}}).
+		Do().Status(http.StatusNoContent)
+	rsp, err = posts.FindOne("1", api2go.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p = rsp.Result().(*post)
+	if len(p.Comments) != 2 || p.Comments[0].ID != "c2" ||
+		p.Comments[1].ID != "c3" {
+		t.Errorf("Expect comments c2 and c3 once but got %v.", p.Comments)
+	}
+	if p.Author == nil || p.Author.ID != "7" {
+		t.Errorf("Expect author 7 but got %v.", p.Author)
+	}
+	srv.PATCH(t, "/v1/posts/1/relationships/author").
+		JSON(map[string]interface{}{
+			"data": map[string]string{"type": "users", "id": "7"}}).
+		Do().Status(http.StatusNoContent)
+	srv.PATCH(t, "/v1/posts/2/relationships/author").
+		JSON(map[string]interface{}{
+			"data": map[string]string{"type": "users", "id": "7"}}).
+		Do().Status(http.StatusNotFound)
+
+	missing, err := posts.MissingIDs([]string{"1", "2", "3"}, api2go.Request{})
+	if err != nil || fmt.Sprint(missing) != "[2 3]" {
+		t.Errorf("Expect missing posts [2 3] but got %v %v.", missing, err)
+	}
+	srv.PATCH(t, "/v1/posts/1/relationships/comments").
+		Linkage("comments").
+		Do().Status(http.StatusNoContent)
+	srv.POST(t, "/v1/posts/2/relationships/comments").
+		Linkage("comments", "c1").
+		Do().Status(http.StatusNotFound)
+	rsp, _ = posts.FindOne("1", api2go.Request{})
+	if p = rsp.Result().(*post); len(p.Comments) != 0 {
+		t.Errorf("Expect no comments but got %v.", p.Comments)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	db := open(t)
+	m, err := sqlstore.NewMapping(&post{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqlstore.NewWithMapping(db, m).Create(&post{Title: "x"},
+		api2go.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	// a mapping of relationships only writes no row of posts
+	m.Columns, m.ToOne = nil, nil
+	posts := sqlstore.NewWithMapping(db, m)
+	_, err = posts.Update(&post{ID: "1", Comments: []*comment{{ID: "a"}}},
+		api2go.Request{})
+	if err != nil {
+		t.Errorf("Expect the update of the comments but got %v.", err)
+	}
+	_, err = posts.Update(&post{ID: "2"}, api2go.Request{})
+	if e, ok := err.(api2go.HTTPError); !ok ||
+		e.Status() != http.StatusNotFound {
+		t.Errorf("Expect 404 for a missing post but got %v.", err)
+	}
+}
+
+func TestReturning(t *testing.T) {
+	posts := newPosts(t)
+	posts.Returning = true
+	for _, exp := range []string{"1", "2"} {
+		rsp, err := posts.Create(&post{Title: "x"}, api2go.Request{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id := rsp.Result().(*post).ID; id != exp {
+			t.Errorf("Expect the ID %s but got %s.", exp, id)
+		}
+	}
+}
+
+func TestGroupLinked(t *testing.T) {
+	db := open(t)
+	g := sqlstore.NewGroup()
+	posts, err := g.Add(db, &post{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	comments, err := g.Add(db, &comment{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := g.Add(db, &user{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"c1", "c2", "c3"} {
+		if _, err := comments.Create(&comment{ID: id},
+			api2go.Request{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := posts.Create(&post{Title: "x", Author: &user{ID: "7"},
+		Comments: []*comment{{ID: "c1"}, {ID: "c3"}}},
+		api2go.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	srv := api2gotest.NewServer("v1").
+		AddResource(&post{}, posts).
+		AddResource(&comment{}, comments).
+		AddResource(&user{}, users)
+	srv.GET(t, "/v1/posts/1/comments").Do().
+		Status(http.StatusOK).
+		DataIDs("c1", "c3")
+	srv.GET(t, "/v1/posts/1/comments").Query("page[offset]", "1").
+		Query("page[limit]", "1").Do().
+		Status(http.StatusOK).
+		DataIDs("c3")
+	srv.GET(t, "/v1/posts/1/author").Do().
+		Status(http.StatusOK).
+		DataIDs("7")
+	srv.GET(t, "/v1/posts/2/comments").Do().
+		Status(http.StatusNotFound)
+}
+
+func TestRollback(t *testing.T) {
+	posts := newPosts(t)
+	_, err := posts.Create(&post{Title: "x",
+		Comments: []*comment{{ID: "a"}}}, api2go.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := posts.Mapping()
+	m.ToMany[0].Table = "missing_table"
+	_, err = posts.Update(&post{ID: "1", Title: "y"}, api2go.Request{})
+	if err == nil {
+		t.Fatal("Expect an error for the missing join table.")
+	}
+	m.ToMany[0].Table = "post_comments"
+	found, err := posts.FindOne("1", api2go.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := found.Result().(*post); p.Title != "x" || len(p.Comments) != 1 {
+		t.Errorf("Expect the update to be rolled back but got %+v.", p)
+	}
+}
+
+func TestSparseFieldset(t *testing.T) {
+	posts := newPosts(t)
+	_, err := posts.Create(&post{Title: "x", Views: 3,
+		Comments: []*comment{{ID: "a"}}}, api2go.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp, err := posts.FindAll(api2go.Request{
+		Fields: map[string][]string{"posts": {"title"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := rsp.Result().([]*post)[0]
+	if p.Title != "x" || p.Views != 0 || p.Comments != nil {
+		t.Errorf("Expect only the title to be loaded but got %+v.", p)
+	}
+}
+
+func TestCreateConflict(t *testing.T) {
+	posts := newPosts(t)
+	if _, err := posts.Create(&post{ID: "3", Title: "x"},
+		api2go.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := posts.Create(&post{ID: "3", Title: "y"}, api2go.Request{})
+	if e, ok := err.(api2go.HTTPError); !ok || e.Status() != http.StatusConflict {
+		t.Errorf("Expect 409 for a taken ID but got %v.", err)
+	}
+	found, err := posts.FindOne("3", api2go.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := found.Result().(*post); p.Title != "x" {
+		t.Errorf("Expect the first post to be kept but got %+v.", p)
+	}
+}
+
+// TestRelationshipSparseFieldset reads the linkage from the complete post,
+// the sparse fieldset of posts applies to primary data only.
+func TestRelationshipSparseFieldset(t *testing.T) {
+	posts := newPosts(t)
+	if _, err := posts.Create(&post{Title: "x", Author: &user{ID: "7"},
+		Comments: []*comment{{ID: "c1"}, {ID: "c2"}}},
+		api2go.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	srv := api2gotest.NewServer("v1").AddResource(&post{}, posts)
+	srv.GET(t, "/v1/posts/1/relationships/comments").
+		Query("fields[posts]", "title").Do().
+		Status(http.StatusOK).
+		DataIDs("c1", "c2")
+	srv.GET(t, "/v1/posts/1/relationships/author").
+		Query("fields[posts]", "title").Do().
+		Status(http.StatusOK).
+		DataIDs("7")
+}
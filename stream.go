@@ -0,0 +1,170 @@
+package api2go
+
+import (
+	"io"
+
+	"github.com/cention-sany/jsonapi"
+)
+
+// The ResultIterator interface can be optionally implemented by the result
+// of a FindAll or PaginatedFindAll Responder, the value returned by its
+// Result method, to stream large collections. Its objects are marshalled and
+// written to the response one by one instead of into one document, so that
+// memory use does not grow with the size of the collection. Sparse fieldsets
+// apply to every object, meta and links of the Responder are written after
+// data. If the iterator implements io.Closer it is closed at the end.
+//
+// The status and headers are written after the first object was read, so an
+// error of the data source after that cuts the response off and is only
+// logged. Requests with the include parameter read the whole collection
+// first, as the included resources depend on all objects.
+type ResultIterator interface {
+	// Next advances to the next object. It returns false at the end of the
+	// collection or on an error.
+	Next() bool
+	// Value returns the current object.
+	Value() interface{}
+	// Err returns the error which stopped Next, if any.
+	Err() error
+}
+
+// buffered tells if a ResultIterator must be read as a whole for the
+// request.
+func buffered(c *routeContext) bool {
+	return len(includePaths(c)) > 0
+}
+
+// collect reads all objects of it and closes it.
+func collect(it ResultIterator) ([]interface{}, error) {
+	if closer, ok := it.(io.Closer); ok {
+		defer closer.Close()
+	}
+	objs := []interface{}{}
+	for it.Next() {
+		objs = append(objs, it.Value())
+	}
+	return objs, it.Err()
+}
+
+// stream writes the collection of it as the data of the response document,
+// followed by the included resources of the objects and by the links and
+// meta of obj. links overrides the links of obj if set.
+func (res *resource) stream(c *routeContext, obj Responder, it ResultIterator,
+	info information, status int, links *jsonapi.Links) error {
+	if closer, ok := it.(io.Closer); ok {
+		defer closer.Close()
+	}
+	query := c.Request.URL.Query()
+	fields := parseQueryFields(&query)
+	if err := res.api.checkFields(fields); err != nil {
+		return err
+	}
+	more := it.Next()
+	if err := it.Err(); err != nil {
+		return err
+	}
+	if links == nil {
+		if objWithLinks, ok := obj.(LinksResponder); ok {
+			links = objWithLinks.Links(c.Request, info)
+		}
+	}
+	var meta *jsonapi.Meta
+	if metable, ok := obj.(Metable); ok {
+		meta = metable.Metadata()
+	}
+
+	span := startSpan(c, SpanStream)
+	w := c.Writer
+	w.Header().Set("Content-Type", res.api.ContentType)
+	w.WriteHeader(status)
+	included := newNodeSet(nil)
+	buf := getBuffer()
+	defer putBuffer(buf)
+	codec := res.api.codec()
+	write := func(prefix string, v interface{}) error {
+		buf.Reset()
+		buf.WriteString(prefix)
+		if err := codec.Encode(buf, v); err != nil {
+			return err
+		}
+		_, err := w.Write(buf.Bytes())
+		return err
+	}
+	// the status is sent, errors can only cut the document off
+	err := func() error {
+		if _, err := io.WriteString(w, `{"data":[`); err != nil {
+			return err
+		}
+		for sep := ""; more; sep = "," {
+			one, err := marshalOne(it.Value(), info)
+			if err != nil {
+				return err
+			}
+			res.api.trimNode(one.Data, fields)
+			if err := write(sep, one.Data); err != nil {
+				return err
+			}
+			included.add(one.Included...)
+			more = it.Next()
+		}
+		if err := it.Err(); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "]"); err != nil {
+			return err
+		}
+		if len(included.list) > 0 {
+			for _, node := range included.list {
+				res.api.trimNode(node, fields)
+			}
+			if err := write(`,"included":`, included.list); err != nil {
+				return err
+			}
+		}
+		if links != nil && len(*links) > 0 {
+			if err := write(`,"links":`, links); err != nil {
+				return err
+			}
+		}
+		if meta != nil && len(*meta) > 0 {
+			if err := write(`,"meta":`, meta); err != nil {
+				return err
+			}
+		}
+		_, err := io.WriteString(w, "}")
+		return err
+	}()
+	endSpan(span, err)
+	if err != nil {
+		res.api.logger().Println("api2go: streamed response cut off:", err)
+	}
+	return nil
+}
+
+// checkFields returns the 400 HTTPError for the fields[type] parameters of
+// types added to the API which name fields the type does not have.
+func (api *API) checkFields(fields map[string][]string) error {
+	wrongFields := map[string][]string{}
+	for typ, names := range fields {
+		res := api.resource(typ)
+		if res == nil {
+			continue
+		}
+		for _, name := range names {
+			if !res.info.hasField(name) {
+				wrongFields[typ] = append(wrongFields[typ], name)
+			}
+		}
+	}
+	if len(wrongFields) > 0 {
+		return invalidFields(wrongFields)
+	}
+	return nil
+}
+
+// trimNode removes the writeonly attributes of node and trims it to the
+// sparse fieldset of its type. Wrong fields are checked by checkFields.
+func (api *API) trimNode(node *jsonapi.Node, fields map[string][]string) {
+	api.hideWriteOnly(node)
+	api.replaceFields(&fields, node)
+}
@@ -0,0 +1,119 @@
+package api2go_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+
+	. "github.com/cention-sany/api2go"
+	"github.com/cention-sany/jsonapi"
+)
+
+type postIterator struct {
+	n, i   int
+	failAt int
+	closed bool
+}
+
+func (it *postIterator) Next() bool {
+	if it.i == it.failAt {
+		return false
+	}
+	it.i++
+	return it.i <= it.n
+}
+
+func (it *postIterator) Value() interface{} {
+	return &post{ID: strconv.Itoa(it.i), Title: "post " + strconv.Itoa(it.i)}
+}
+
+func (it *postIterator) Err() error {
+	if it.i == it.failAt {
+		return errors.New("connection lost")
+	}
+	return nil
+}
+
+func (it *postIterator) Close() error {
+	it.closed = true
+	return nil
+}
+
+type streamResponse struct {
+	it *postIterator
+}
+
+func (r streamResponse) Result() interface{} { return r.it }
+func (r streamResponse) StatusCode() int     { return http.StatusOK }
+
+func (r streamResponse) Metadata() *jsonapi.Meta {
+	return &jsonapi.Meta{"total": r.it.n}
+}
+
+type streamSource struct {
+	panickySource
+	it *postIterator
+}
+
+func (s *streamSource) FindAll(req Request) (Responder, error) {
+	return streamResponse{s.it}, nil
+}
+
+func TestStream(t *testing.T) {
+	router := NewHTTPRouter("/v1")
+	api := NewAPI("v1", NewStaticResolver(""))
+	source := &streamSource{}
+	api.AddResourceWithRouter(router, &post{}, source)
+
+	source.it = &postIterator{n: 3, failAt: -1}
+	rec := serve(router, "GET", "/v1/posts?fields[posts]=", "")
+	var doc struct {
+		Data []struct {
+			ID         string
+			Attributes map[string]interface{}
+		}
+		Meta map[string]interface{}
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Expect a JSON document but got %s: %v", rec.Body, err)
+	}
+	ids := []string{}
+	for _, d := range doc.Data {
+		ids = append(ids, d.ID)
+		if len(d.Attributes) > 0 {
+			t.Errorf("Expect no attributes but got %v.", d.Attributes)
+		}
+	}
+	if rec.Code != http.StatusOK || !reflect.DeepEqual(ids,
+		[]string{"1", "2", "3"}) || doc.Meta["total"] != 3.0 {
+		t.Errorf("Expect posts 1 to 3 and meta but got %d: %s", rec.Code,
+			rec.Body)
+	}
+	if !source.it.closed {
+		t.Error("Expect the iterator to be closed.")
+	}
+
+	source.it = &postIterator{n: 3, failAt: -1}
+	rec = serve(router, "GET", "/v1/posts?fields[posts]=colour", "")
+	if rec.Code != http.StatusBadRequest || source.it.i != 0 {
+		t.Errorf("Expect status %d before streaming but got %d: %s",
+			http.StatusBadRequest, rec.Code, rec.Body)
+	}
+
+	source.it = &postIterator{n: 3, failAt: 0}
+	rec = serve(router, "GET", "/v1/posts", "")
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expect status %d for a failing first object but got %d.",
+			http.StatusInternalServerError, rec.Code)
+	}
+
+	source.it = &postIterator{n: 3, failAt: 2}
+	rec = serve(router, "GET", "/v1/posts", "")
+	if rec.Code != http.StatusOK || json.Valid(rec.Body.Bytes()) {
+		t.Errorf("Expect a cut off document but got %d: %s", rec.Code,
+			rec.Body)
+	}
+}
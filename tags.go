@@ -0,0 +1,376 @@
+package api2go
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	ja "github.com/cention-sany/jsonapi"
+)
+
+// Models without MarshalNode and UnmarshalNode are marshalled by their
+// jsonapi tags with the cached typeInfo, the same way as the jsonapi library
+// does it, but without reading the tags on every call.
+
+const iso8601TimeFormat = "2006-01-02T15:04:05Z"
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	timePtrType = reflect.TypeOf(&time.Time{})
+
+	errBadID      = errors.New("api2go: id should be a string, int or uint")
+	errBadTime    = errors.New("api2go: only numbers can be parsed as dates, unix timestamps")
+	errBadISO8601 = errors.New("api2go: only strings can be parsed as dates, ISO8601 timestamps")
+)
+
+// taggedInfo returns the typeInfo of the models of type t, which are
+// marshalled by their tags, nil if t is neither a struct nor a pointer to one
+// with a primary tag.
+func taggedInfo(t reflect.Type) *typeInfo {
+	if t == nil || !cacheTypeInfos {
+		return nil
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	ti, err := typeInfoOf(t)
+	if err != nil {
+		return nil
+	}
+	return ti
+}
+
+// newObject returns a pointer to a new object of the type.
+func (ti *typeInfo) newObject() interface{} {
+	return reflect.New(ti.typ).Interface()
+}
+
+// pointerTo returns a pointer to a copy of obj, an object of the type.
+func (ti *typeInfo) pointerTo(obj interface{}) interface{} {
+	ptr := reflect.New(ti.typ)
+	ptr.Elem().Set(reflect.ValueOf(obj))
+	return ptr.Interface()
+}
+
+// tagMarshaler marshals models by their tags. The related models are added
+// to included once per type and ID.
+type tagMarshaler struct {
+	si       ja.ServerInformation
+	included []*ja.Node
+	seen     map[string]bool
+}
+
+func newTagMarshaler(si ja.ServerInformation) *tagMarshaler {
+	return &tagMarshaler{si: si, seen: map[string]bool{}}
+}
+
+// node returns the resource object of model, an object of the type of ti or
+// a pointer to it, nil for a nil pointer.
+func (m *tagMarshaler) node(ti *typeInfo, model interface{}) (*ja.Node,
+	error) {
+	v := reflect.ValueOf(model)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	id, err := formatID(v.Field(ti.id))
+	if err != nil {
+		return nil, err
+	}
+	node := &ja.Node{Type: ti.name, ID: id}
+	if ti.clientID >= 0 {
+		node.ClientID = v.Field(ti.clientID).String()
+	}
+	if len(ti.fields) > 0 {
+		node.Attributes = make(map[string]interface{}, len(ti.fields))
+	}
+	for _, f := range ti.fields {
+		if value, ok := f.value(v.Field(f.index)); ok {
+			node.Attributes[f.name] = value
+		}
+	}
+	for _, r := range ti.relations {
+		fv := v.Field(r.index)
+		if r.omitEmpty && (r.isMany && fv.Len() == 0 ||
+			!r.isMany && isNil(fv)) {
+			continue
+		}
+		if node.Relationships == nil {
+			node.Relationships = make(map[string]interface{},
+				len(ti.relations))
+		}
+		if !r.isMany {
+			related, err := m.related(fv)
+			if err != nil {
+				return nil, err
+			}
+			node.Relationships[r.name] = &ja.RelationshipOneNode{Data: related}
+			continue
+		}
+		data := make([]*ja.Node, 0, fv.Len())
+		for i := 0; i < fv.Len(); i++ {
+			related, err := m.related(fv.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			if related != nil {
+				data = append(data, related)
+			}
+		}
+		node.Relationships[r.name] = &ja.RelationshipManyNode{Data: data}
+	}
+	return withLinks(node, model, m.si), nil
+}
+
+// related adds the related model v to included and returns its resource
+// identifier, nil for a nil pointer.
+func (m *tagMarshaler) related(v reflect.Value) (*ja.Node, error) {
+	if isNil(v) {
+		return nil, nil
+	}
+	model := v.Interface()
+	if mn, ok := model.(MarshalNode); ok {
+		node := nodeOf(mn, m.si)
+		m.include(node.Type+"/"+node.ID, node)
+		return &ja.Node{Type: node.Type, ID: node.ID}, nil
+	}
+	ti, err := typeInfoOf(reflect.Indirect(v).Type())
+	if err != nil {
+		return nil, err
+	}
+	id, err := formatID(reflect.Indirect(v).Field(ti.id))
+	if err != nil {
+		return nil, err
+	}
+	key := ti.name + "/" + id
+	if !m.seen[key] {
+		// marked before the relationships are visited, which may lead back
+		// to model
+		m.seen[key] = true
+		node, err := m.node(ti, model)
+		if err != nil {
+			return nil, err
+		}
+		m.included = append(m.included, node)
+	}
+	return &ja.Node{Type: ti.name, ID: id}, nil
+}
+
+// include adds node to included unless a node with key is already there.
+func (m *tagMarshaler) include(key string, node *ja.Node) {
+	if m.seen[key] {
+		return
+	}
+	m.seen[key] = true
+	m.included = append(m.included, node)
+}
+
+// value returns the attribute value of the field value v, false if it is
+// left out.
+func (f attributeField) value(v reflect.Value) (interface{}, bool) {
+	switch v.Type() {
+	case timeType:
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return nil, false
+		}
+		return f.formatTime(t), true
+	case timePtrType:
+		if v.IsNil() {
+			return nil, !f.omitEmpty
+		}
+		t := v.Interface().(*time.Time)
+		if t.IsZero() && f.omitEmpty {
+			return nil, false
+		}
+		return f.formatTime(*t), true
+	}
+	if f.omitEmpty && v.IsZero() {
+		return nil, false
+	}
+	return v.Interface(), true
+}
+
+func (f attributeField) formatTime(t time.Time) interface{} {
+	if f.iso8601 {
+		return t.UTC().Format(iso8601TimeFormat)
+	}
+	return t.Unix()
+}
+
+// unmarshal sets the field value v from the attribute value raw.
+func (f attributeField) unmarshal(raw json.RawMessage, v reflect.Value) error {
+	switch v.Type() {
+	case timeType, timePtrType:
+		t, err := f.parseTime(raw)
+		if err != nil {
+			return err
+		}
+		if v.Kind() == reflect.Ptr {
+			v.Set(reflect.ValueOf(&t))
+		} else {
+			v.Set(reflect.ValueOf(t))
+		}
+		return nil
+	}
+	return json.Unmarshal(raw, v.Addr().Interface())
+}
+
+func (f attributeField) parseTime(raw json.RawMessage) (time.Time, error) {
+	if f.iso8601 {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return time.Time{}, errBadISO8601
+		}
+		t, err := time.Parse(iso8601TimeFormat, s)
+		if err != nil {
+			return time.Time{}, errBadISO8601
+		}
+		return t, nil
+	}
+	var unix float64
+	if err := json.Unmarshal(raw, &unix); err != nil {
+		return time.Time{}, errBadTime
+	}
+	return time.Unix(int64(unix), 0), nil
+}
+
+// unmarshalNode sets the object v of the type from the resource object
+// node, as returned by resourceObject.node. Attributes and relationships
+// which are not in node are left unchanged.
+func (ti *typeInfo) unmarshalNode(node *ja.Node, v reflect.Value) error {
+	if node.ID != "" {
+		if node.Type != ti.name {
+			return fmt.Errorf("api2go: can not unmarshal an object of type %q into %q",
+				node.Type, ti.name)
+		}
+		if err := parseID(node.ID, v.Field(ti.id)); err != nil {
+			return err
+		}
+	}
+	if ti.clientID >= 0 && node.ClientID != "" {
+		v.Field(ti.clientID).SetString(node.ClientID)
+	}
+	for _, f := range ti.fields {
+		raw, ok := node.Attributes[f.name].(json.RawMessage)
+		if !ok || bytes.Equal(raw, []byte("null")) {
+			continue
+		}
+		if err := f.unmarshal(raw, v.Field(f.index)); err != nil {
+			return err
+		}
+	}
+	for _, r := range ti.relations {
+		fv := v.Field(r.index)
+		switch rel := node.Relationships[r.name].(type) {
+		case *ja.RelationshipOneNode:
+			if r.isMany || rel.Data == nil {
+				continue
+			}
+			related, err := newRelated(fv.Type(), rel.Data)
+			if err != nil {
+				return err
+			}
+			fv.Set(related)
+		case *ja.RelationshipManyNode:
+			if !r.isMany {
+				continue
+			}
+			models := reflect.Zero(fv.Type())
+			for _, n := range rel.Data {
+				related, err := newRelated(fv.Type().Elem(), n)
+				if err != nil {
+					return err
+				}
+				models = reflect.Append(models, related)
+			}
+			fv.Set(models)
+		}
+	}
+	return nil
+}
+
+// newRelated returns a new related object of type t with the resource
+// identifier n.
+func newRelated(t reflect.Type, n *ja.Node) (reflect.Value, error) {
+	elem := t
+	if t.Kind() == reflect.Ptr {
+		elem = t.Elem()
+	}
+	ti, err := typeInfoOf(elem)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	ptr := reflect.New(elem)
+	if err := ti.unmarshalNode(n, ptr.Elem()); err != nil {
+		return reflect.Value{}, err
+	}
+	if t.Kind() == reflect.Ptr {
+		return ptr, nil
+	}
+	return ptr.Elem(), nil
+}
+
+// formatID returns the ID of the primary field value v.
+func formatID(v reflect.Value) (string, error) {
+	v = reflect.Indirect(v)
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	}
+	return "", errBadID
+}
+
+// parseID sets the primary field value v to id.
+func parseID(id string, v reflect.Value) error {
+	if v.Kind() == reflect.Ptr {
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(id)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		n, err := strconv.ParseInt(id, 10, v.Type().Bits())
+		if err != nil {
+			return errBadID
+		}
+		v.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		n, err := strconv.ParseUint(id, 10, v.Type().Bits())
+		if err != nil {
+			return errBadID
+		}
+		v.SetUint(n)
+		return nil
+	}
+	return errBadID
+}
+
+// isNil tells if v is a nil pointer or interface.
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package api2go_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	. "github.com/cention-sany/api2go"
+	"github.com/cention-sany/jsonapi"
+)
+
+type tagAuthor struct {
+	ID   int64  `jsonapi:"primary,authors"`
+	Name string `jsonapi:"attr,name"`
+}
+
+type tagComment struct {
+	ID   uint   `jsonapi:"primary,comments"`
+	Text string `jsonapi:"attr,text"`
+}
+
+type tagPost struct {
+	ID       string        `jsonapi:"primary,posts"`
+	Title    string        `jsonapi:"attr,title"`
+	Views    int           `jsonapi:"attr,views,omitempty"`
+	Likes    int           `jsonapi:"attr,likes,omitempty"`
+	Draft    bool          `jsonapi:"attr,draft"`
+	Tags     []string      `jsonapi:"attr,tags"`
+	Created  time.Time     `jsonapi:"attr,created,iso8601"`
+	Updated  *time.Time    `jsonapi:"attr,updated"`
+	Deleted  *time.Time    `jsonapi:"attr,deleted,omitempty"`
+	Author   *tagAuthor    `jsonapi:"relation,author"`
+	Editor   *tagAuthor    `jsonapi:"relation,editor,omitempty"`
+	Reviewer *tagAuthor    `jsonapi:"relation,reviewer"`
+	Comments []*tagComment `jsonapi:"relation,comments"`
+	Likers   []*tagAuthor  `jsonapi:"relation,likers,omitempty"`
+}
+
+type tagSI struct{}
+
+func (tagSI) GetBaseURL() string { return "http://localhost" }
+func (tagSI) GetPrefix() string  { return "/v1/" }
+
+func newTagPost() *tagPost {
+	updated := time.Unix(1500000000, 0)
+	author := &tagAuthor{ID: 7, Name: "Ann"}
+	return &tagPost{
+		ID:      "1",
+		Title:   "Hello",
+		Views:   5,
+		Tags:    []string{"a", "b"},
+		Created: time.Date(2017, 7, 14, 2, 40, 0, 0, time.UTC),
+		Updated: &updated,
+		Author:  author,
+		Comments: []*tagComment{
+			{ID: 2, Text: "first"},
+			{ID: 3, Text: "second"},
+		},
+		Likers: []*tagAuthor{author},
+	}
+}
+
+// encodeSorted encodes payload with the included resources sorted, which
+// the jsonapi library adds in random order.
+func encodeSorted(t *testing.T, payload *jsonapi.OnePayload) string {
+	sort.Slice(payload.Included, func(i, j int) bool {
+		a, b := payload.Included[i], payload.Included[j]
+		return a.Type+"/"+a.ID < b.Type+"/"+b.ID
+	})
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestMarshalTagged(t *testing.T) {
+	for _, v := range []interface{}{newTagPost(), *newTagPost(),
+		&tagPost{ID: "2"}} {
+		payload, err := MarshalTagged(v, tagSI{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ptr := reflect.New(reflect.TypeOf(v))
+		ptr.Elem().Set(reflect.ValueOf(v))
+		if ptr.Elem().Kind() == reflect.Ptr {
+			ptr = ptr.Elem()
+		}
+		expected, err := jsonapi.MarshalOneWithSI(ptr.Interface(), tagSI{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, want := encodeSorted(t, payload), encodeSorted(t, expected)
+		if got != want {
+			t.Errorf("Expect the document of the jsonapi library\n%s\nbut got\n%s",
+				want, got)
+		}
+	}
+}
+
+func TestUnmarshalTagged(t *testing.T) {
+	payload, err := MarshalTagged(newTagPost(), tagSI{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload.Included = nil
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got, expected tagPost
+	if err := UnmarshalTagged(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if err := jsonapi.UnmarshalPayload(bytes.NewReader(body),
+		&expected); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expect the object of the jsonapi library\n%+v\nbut got\n%+v",
+			expected, got)
+	}
+	if got.Title != "Hello" || got.Author == nil || got.Author.ID != 7 ||
+		len(got.Comments) != 2 || got.Comments[1].ID != 3 {
+		t.Errorf("Expect the post back but got %+v", got)
+	}
+
+	for _, body := range []string{
+		`{"data":{"type":"authors","id":"1"}}`,
+		`{"data":{"type":"posts","id":"1","attributes":{"created":1}}}`,
+		`{"data":{"type":"posts","id":"1","attributes":{"views":"5"}}}`,
+		`{"data":{"type":"posts","id":"1","relationships":` +
+			`{"author":{"data":{"type":"authors","id":"x"}}}}}`,
+	} {
+		if err := UnmarshalTagged([]byte(body), &tagPost{}); err == nil {
+			t.Errorf("Expect an error for %s", body)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package api2go
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Span names used by api2go for the phases of a request. The request span
+// itself is named "api2go." followed by the action, e.g. "api2go.read".
+const (
+	SpanFindOne                = "FindOne"
+	SpanFindMany               = "FindMany"
+	SpanFindAll                = "FindAll"
+	SpanCount                  = "Count"
+	SpanExists                 = "Exists"
+	SpanPaginatedFindAll       = "PaginatedFindAll"
+	SpanCreate                 = "Create"
+	SpanUpdate                 = "Update"
+	SpanDelete                 = "Delete"
+	SpanGenerateID             = "GenerateID"
+	SpanReplaceRelationship    = "ReplaceRelationship"
+	SpanAddToRelationship      = "AddToRelationship"
+	SpanRemoveFromRelationship = "RemoveFromRelationship"
+	SpanMissingIDs             = "MissingIDs"
+	SpanUnmarshalPayload       = "jsonapi.UnmarshalPayload"
+	SpanMarshalToDoc           = "marshalToDoc"
+	SpanFilterSparseFields     = "filterSparseFields"
+	SpanEncode                 = "encode" // encoding of the response by API.Codec
+	SpanStream                 = "stream"
+)
+
+// Span is a timed operation started by a Tracer.
+type Span interface {
+	// SetAttribute attaches a key value pair to the span.
+	SetAttribute(key string, value interface{})
+	// End finishes the span. It is called exactly once.
+	End()
+}
+
+// Tracer can be set on API to trace every request and its phases. Start
+// creates a span as a child of any span carried by ctx and returns a context
+// carrying the new span. Data sources get that context through
+// Request.Context() and can create child spans with StartSpan.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+type tracerKey struct{}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, interface{}) {}
+func (noopSpan) End()                             {}
+
+// NoopTracer is a Tracer which does nothing, e.g. for tests of code which
+// needs one. A nil API.Tracer does not use it, but skips tracing altogether.
+type NoopTracer struct{}
+
+// Start implements Tracer.
+func (NoopTracer) Start(ctx context.Context, name string) (context.Context,
+	Span) {
+	return ctx, noopSpan{}
+}
+
+// StartSpan starts a span with the Tracer of the API that handles the request
+// of ctx. It is safe to call without a Tracer, the span does nothing then.
+func StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	t, ok := ctx.Value(tracerKey{}).(Tracer)
+	if !ok {
+		return ctx, noopSpan{}
+	}
+	return t.Start(ctx, name)
+}
+
+// endSpan records err on span, if any, and ends it.
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.SetAttribute("error", err.Error())
+	}
+	span.End()
+}
+
+// startSpan starts a phase span below the request span of c.
+func startSpan(c *routeContext, name string) Span {
+	_, span := StartSpan(c.Request.Context(), name)
+	return span
+}
+
+// sourceRequest starts a span for a data source call and builds the Request
+// for it, so that the data source sees the span through the Request and
+// Request.Context().
+func sourceRequest(c *routeContext, name string) (Request, Span) {
+	ctx, span := StartSpan(c.Request.Context(), name)
+	req := buildReqParams(c)
+	req.APIContexter = &apiContext{Context: ctx, store: c.store}
+	req.Request = req.Request.WithContext(ctx)
+	return req, span
+}
+
+// trace wraps every route and starts the request span.
+func (api *API) trace(resource, action string, next handler) handler {
+	return func(c *routeContext) {
+		if api.Tracer == nil {
+			next(c)
+			return
+		}
+		ctx := context.WithValue(c.Request.Context(), tracerKey{}, api.Tracer)
+		ctx, span := api.Tracer.Start(ctx, "api2go."+action)
+		span.SetAttribute("api2go.resource", resource)
+		span.SetAttribute("api2go.action", action)
+		c.Request = c.Request.WithContext(ctx)
+		next(c)
+		span.SetAttribute("http.status_code", c.Writer.Status())
+		span.End()
+	}
+}
+
+// RecordedSpan is a finished or running span of RecordingTracer.
+type RecordedSpan struct {
+	Name       string
+	Parent     *RecordedSpan
+	Attributes map[string]interface{}
+	StartTime  time.Time
+	EndTime    time.Time
+	Ended      bool
+	tracer     *RecordingTracer
+}
+
+// SetAttribute implements Span.
+func (s *RecordedSpan) SetAttribute(key string, value interface{}) {
+	s.tracer.mu.Lock()
+	s.Attributes[key] = value
+	s.tracer.mu.Unlock()
+}
+
+// End implements Span.
+func (s *RecordedSpan) End() {
+	s.tracer.mu.Lock()
+	s.EndTime = time.Now()
+	s.Ended = true
+	s.tracer.mu.Unlock()
+}
+
+type recordedSpanKey struct{}
+
+// RecordingTracer is an in-memory Tracer that keeps every span, meant for
+// tests.
+type RecordingTracer struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+// Start implements Tracer.
+func (t *RecordingTracer) Start(ctx context.Context, name string) (
+	context.Context, Span) {
+	parent, _ := ctx.Value(recordedSpanKey{}).(*RecordedSpan)
+	s := &RecordedSpan{
+		Name:       name,
+		Parent:     parent,
+		Attributes: map[string]interface{}{},
+		StartTime:  time.Now(),
+		tracer:     t,
+	}
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+	return context.WithValue(ctx, recordedSpanKey{}, s), s
+}
+
+// Spans returns all started spans in start order.
+func (t *RecordingTracer) Spans() []*RecordedSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*RecordedSpan(nil), t.spans...)
+}
+
+// Reset forgets all recorded spans.
+func (t *RecordingTracer) Reset() {
+	t.mu.Lock()
+	t.spans = nil
+	t.mu.Unlock()
+}
@@ -0,0 +1,62 @@
+package api2go_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/cention-sany/api2go"
+	"github.com/gin-gonic/gin"
+)
+
+type tracedPostSource struct {
+	*postSource
+}
+
+func (s tracedPostSource) FindOne(id string, req Request) (Responder, error) {
+	_, span := StartSpan(req.Context(), "db.select")
+	defer span.End()
+	return s.postSource.FindOne(id, req)
+}
+
+func TestTracing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	tracer := &RecordingTracer{}
+	api := NewAPI("v1", NewStaticResolver(""))
+	api.Tracer = tracer
+	api.AddResource(r.Group("/v1"), &post{},
+		tracedPostSource{newPostSource("hello")})
+
+	req, _ := http.NewRequest("GET", "/v1/posts/1", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := tracer.Spans()
+	names := map[string]*RecordedSpan{}
+	for _, s := range spans {
+		if !s.Ended {
+			t.Errorf("Expect span %s to be ended.", s.Name)
+		}
+		names[s.Name] = s
+	}
+	root, ok := names["api2go.read"]
+	if !ok {
+		t.Fatalf("Expect request span but got %d spans.", len(spans))
+	}
+	if root.Attributes["http.status_code"] != http.StatusOK {
+		t.Errorf("Expect status attribute 200 but got %v.",
+			root.Attributes["http.status_code"])
+	}
+	for _, name := range []string{SpanFindOne, SpanMarshalToDoc,
+		SpanFilterSparseFields, SpanEncode} {
+		s, ok := names[name]
+		if !ok {
+			t.Errorf("Expect span %s.", name)
+		} else if s.Parent != root {
+			t.Errorf("Expect span %s to be a child of the request span.", name)
+		}
+	}
+	if db, ok := names["db.select"]; !ok || db.Parent != names[SpanFindOne] {
+		t.Error("Expect data source span to be a child of FindOne.")
+	}
+}
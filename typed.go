@@ -0,0 +1,201 @@
+package api2go
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TypedCRUD is the type-safe counterpart of CRUD for resources of type T,
+// which is either a struct like Post or a pointer like *Post. It is
+// registered with AddTypedResource and needs no type assertions:
+//
+//	func (s PostStorage) Create(ctx context.Context, p *Post) (*Post, error)
+//
+// The Request of the call is available through RequestFromContext. Errors
+// are rendered like the ones of CRUD, so an HTTPError sets the status code.
+type TypedCRUD[T Identifier] interface {
+	// FindOne returns the object with id.
+	FindOne(ctx context.Context, id string) (T, error)
+	// Create stores obj and returns the created object, which is answered
+	// with 201 Created.
+	Create(ctx context.Context, obj T) (T, error)
+	// Update stores obj and returns the updated object, which is answered
+	// with 200 OK.
+	Update(ctx context.Context, obj T) (T, error)
+	// Delete removes the object with id, which is answered with 204 No
+	// Content.
+	Delete(ctx context.Context, id string) error
+}
+
+// TypedFindAll can be implemented by a TypedCRUD to serve the resource
+// collection, like FindAll.
+type TypedFindAll[T Identifier] interface {
+	FindAll(ctx context.Context) ([]T, error)
+}
+
+// TypedPaginatedFindAll can be implemented by a TypedCRUD to serve paginated
+// collections, like PaginatedFindAll. The page is read from the Request, see
+// OffsetPage.
+type TypedPaginatedFindAll[T Identifier] interface {
+	PaginatedFindAll(ctx context.Context) (totalCount uint, objs []T,
+		err error)
+}
+
+type requestKey struct{}
+
+// RequestFromContext returns the Request of a TypedCRUD call.
+func RequestFromContext(ctx context.Context) (Request, bool) {
+	req, ok := ctx.Value(requestKey{}).(Request)
+	return req, ok
+}
+
+// AddTypedResource registers a type-safe data source for resources of type
+// T on api. The routes are the same as the ones of API.AddResource, so typed
+// and untyped resources can be mixed on one API.
+func AddTypedResource[T Identifier](api *API, rg *gin.RouterGroup,
+	source TypedCRUD[T]) {
+	api.addResource(GinRouter(rg), typedPrototype[T](), adaptTyped(source))
+}
+
+// AddTypedResourceWithRouter is AddTypedResource for any Router.
+func AddTypedResourceWithRouter[T Identifier](api *API, router Router,
+	source TypedCRUD[T]) {
+	api.addResource(router, typedPrototype[T](), adaptTyped(source))
+}
+
+// typedPrototype returns a zero T, with a pointer to a zero struct if T is a
+// pointer type.
+func typedPrototype[T Identifier]() T {
+	var zero T
+	t := reflect.TypeOf(&zero).Elem()
+	if t.Kind() == reflect.Ptr {
+		return reflect.New(t.Elem()).Interface().(T)
+	}
+	return zero
+}
+
+// adaptTyped returns a CRUD which implements FindAll and PaginatedFindAll
+// exactly if source implements their typed counterparts, as the handlers
+// check for those interfaces.
+func adaptTyped[T Identifier](source TypedCRUD[T]) CRUD {
+	base := typedSource[T]{source: source}
+	all, hasAll := source.(TypedFindAll[T])
+	paged, hasPaged := source.(TypedPaginatedFindAll[T])
+	switch {
+	case hasAll && hasPaged:
+		return struct {
+			typedSource[T]
+			typedFindAll[T]
+			typedPaginatedFindAll[T]
+		}{base, typedFindAll[T]{all}, typedPaginatedFindAll[T]{paged}}
+	case hasAll:
+		return struct {
+			typedSource[T]
+			typedFindAll[T]
+		}{base, typedFindAll[T]{all}}
+	case hasPaged:
+		return struct {
+			typedSource[T]
+			typedPaginatedFindAll[T]
+		}{base, typedPaginatedFindAll[T]{paged}}
+	}
+	return base
+}
+
+func typedContext(req Request) context.Context {
+	var ctx context.Context = req.APIContexter
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, requestKey{}, req)
+}
+
+// typedObject asserts the object unmarshalled by the handlers.
+func typedObject[T Identifier](obj interface{}) (T, error) {
+	t, ok := obj.(T)
+	if !ok {
+		return t, NewHTTPError(fmt.Errorf("api2go: expected %T but got %T", t,
+			obj), "Invalid instance given", http.StatusBadRequest)
+	}
+	return t, nil
+}
+
+type typedSource[T Identifier] struct {
+	source TypedCRUD[T]
+}
+
+// wrapped returns the typed source, which may implement optional interfaces
+// like ClientIDPolicy.
+func (s typedSource[T]) wrapped() interface{} {
+	return s.source
+}
+
+func (s typedSource[T]) FindOne(id string, req Request) (Responder, error) {
+	obj, err := s.source.FindOne(typedContext(req), id)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Res: obj, Code: http.StatusOK}, nil
+}
+
+func (s typedSource[T]) Create(obj interface{}, req Request) (Responder,
+	error) {
+	t, err := typedObject[T](obj)
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.source.Create(typedContext(req), t)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Res: created, Code: http.StatusCreated}, nil
+}
+
+func (s typedSource[T]) Update(obj interface{}, req Request) (Responder,
+	error) {
+	t, err := typedObject[T](obj)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := s.source.Update(typedContext(req), t)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Res: updated, Code: http.StatusOK}, nil
+}
+
+func (s typedSource[T]) Delete(id string, req Request) (Responder, error) {
+	if err := s.source.Delete(typedContext(req), id); err != nil {
+		return nil, err
+	}
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+type typedFindAll[T Identifier] struct {
+	source TypedFindAll[T]
+}
+
+func (s typedFindAll[T]) FindAll(req Request) (Responder, error) {
+	objs, err := s.source.FindAll(typedContext(req))
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Res: objs, Code: http.StatusOK}, nil
+}
+
+type typedPaginatedFindAll[T Identifier] struct {
+	source TypedPaginatedFindAll[T]
+}
+
+func (s typedPaginatedFindAll[T]) PaginatedFindAll(req Request) (uint,
+	Responder, error) {
+	total, objs, err := s.source.PaginatedFindAll(typedContext(req))
+	if err != nil {
+		return 0, nil, err
+	}
+	return total, &Response{Res: objs, Code: http.StatusOK}, nil
+}
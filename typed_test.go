@@ -0,0 +1,129 @@
+package api2go_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/cention-sany/api2go"
+	"github.com/gin-gonic/gin"
+)
+
+type typedPosts struct {
+	posts map[string]*post
+}
+
+func (s *typedPosts) FindAll(ctx context.Context) ([]*post, error) {
+	all := []*post{}
+	for i := 1; i <= len(s.posts); i++ {
+		all = append(all, s.posts[strconv.Itoa(i)])
+	}
+	return all, nil
+}
+
+func (s *typedPosts) FindOne(ctx context.Context, id string) (*post, error) {
+	p, ok := s.posts[id]
+	if !ok {
+		return nil, NewHTTPError(nil, "post not found", http.StatusNotFound)
+	}
+	return p, nil
+}
+
+func (s *typedPosts) Create(ctx context.Context, p *post) (*post, error) {
+	req, _ := RequestFromContext(ctx)
+	if v := req.QueryParams["title"]; len(v) > 0 {
+		p.Title = v[0]
+	}
+	p.ID = strconv.Itoa(len(s.posts) + 1)
+	s.posts[p.ID] = p
+	return p, nil
+}
+
+func (s *typedPosts) Update(ctx context.Context, p *post) (*post, error) {
+	s.posts[p.ID] = p
+	return p, nil
+}
+
+func (s *typedPosts) Delete(ctx context.Context, id string) error {
+	delete(s.posts, id)
+	return nil
+}
+
+type comment struct {
+	ID   string `jsonapi:"primary,comments"`
+	Text string `jsonapi:"attr,text"`
+}
+
+func (c comment) GetID() string { return c.ID }
+
+type typedComments struct{}
+
+func (typedComments) FindOne(ctx context.Context, id string) (comment, error) {
+	return comment{ID: id, Text: "hello"}, nil
+}
+
+func (typedComments) Create(ctx context.Context, c comment) (comment, error) {
+	return c, nil
+}
+
+func (typedComments) Update(ctx context.Context, c comment) (comment, error) {
+	return c, nil
+}
+
+func (typedComments) Delete(ctx context.Context, id string) error {
+	return nil
+}
+
+func TestTypedResource(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := NewAPI("v1", NewStaticResolver(""))
+	rg := r.Group("/v1")
+	AddTypedResource[*post](api, rg, &typedPosts{posts: map[string]*post{}})
+	AddTypedResource[comment](api, rg, typedComments{})
+	api.AddResource(rg, panicky{}, panickySource{})
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, target, strings.NewReader(body))
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("POST", "/v1/posts?title=typed",
+		`{"data":{"type":"posts","attributes":{"title":"x"}}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expect status %d but got %d: %s", http.StatusCreated,
+			rec.Code, rec.Body)
+	}
+	rec = do("GET", "/v1/posts", "")
+	var doc struct {
+		Data []struct {
+			ID         string
+			Attributes map[string]interface{}
+		}
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Data) != 1 || doc.Data[0].Attributes["title"] != "typed" {
+		t.Errorf("Expect the created post in %s", rec.Body)
+	}
+	if rec = do("GET", "/v1/posts/2", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expect status %d but got %d.", http.StatusNotFound, rec.Code)
+	}
+	if rec = do("DELETE", "/v1/posts/1", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("Expect status %d but got %d.", http.StatusNoContent, rec.Code)
+	}
+	if rec = do("DELETE", "/v1/comments/3", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("Expect status %d but got %d.", http.StatusNoContent, rec.Code)
+	}
+	// without TypedFindAll there is no collection
+	if rec = do("GET", "/v1/comments", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expect status %d but got %d.", http.StatusNotFound, rec.Code)
+	}
+}